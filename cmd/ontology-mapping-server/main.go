@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"ontology-mapping-go-lib/operations"
+	"os"
+	"time"
+)
+
+func main() {
+	var addr = flag.String("addr", ":8080", "address the server listens on")
+	var token = flag.String("token", os.Getenv("ONTOLOGY_MAPPING_TOKEN"), "bearer token required on every request, disabled when empty")
+	var maxBodyBytes = flag.Int64("max-body-bytes", defaultMaxBodyBytes, "largest request body accepted, in bytes")
+	var requestTimeout = flag.Duration("request-timeout", defaultRequestTimeout, "time budget of the operations of one request")
+	var operationTimeout = flag.Duration("operation-timeout", 0, "time budget of every single operation, disabled when zero")
+	flag.Parse()
+
+	var mappingServer = &server{
+		service:        operations.OperationService{Factory: operations.OperationFactory{}, OperationTimeout: *operationTimeout},
+		token:          *token,
+		maxBodyBytes:   *maxBodyBytes,
+		requestTimeout: *requestTimeout,
+	}
+	var httpServer = &http.Server{
+		Addr:              *addr,
+		Handler:           mappingServer.routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		// the answer is written once the operations are applied
+		WriteTimeout: *requestTimeout + 30*time.Second,
+		IdleTimeout:  2 * time.Minute,
+	}
+	log.Printf("ontology mapping server listening on %s", *addr)
+	log.Fatal(httpServer.ListenAndServe())
+}
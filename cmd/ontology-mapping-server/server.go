@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/operations"
+	"ontology-mapping-go-lib/util"
+	"time"
+)
+
+// defaultMaxBodyBytes bounds the request bodies when server.maxBodyBytes is zero.
+const defaultMaxBodyBytes = 1 << 20
+
+// defaultRequestTimeout bounds the time spent applying the operations of one
+// request when server.requestTimeout is zero.
+const defaultRequestTimeout = 10 * time.Second
+
+type server struct {
+	service        operations.OperationService
+	token          string
+	maxBodyBytes   int64
+	requestTimeout time.Duration
+}
+
+type upApplyRequest struct {
+	Message *flow.UpMessage `json:"message"`
+}
+
+type downApplyRequest struct {
+	Message        *flow.DownMessage `json:"message"`
+	OperationsDown []json.RawMessage `json:"operationsDown"`
+}
+
+// upExplainErrorResponse is the explanation of a mapping that failed, along with
+// the error the mapping answers without explain.
+type upExplainErrorResponse struct {
+	*operations.UpExplanation
+	Error flow.ErrorInfo `json:"error"`
+}
+
+type downExplainErrorResponse struct {
+	*operations.DownExplanation
+	Error flow.ErrorInfo `json:"error"`
+}
+
+type upDiffResponse struct {
+	Message *flow.UpMessage          `json:"message"`
+	Diff    operations.UpMessageDiff `json:"diff"`
+}
+
+type downDiffResponse struct {
+	Message *flow.DownMessage          `json:"message"`
+	Diff    operations.DownMessageDiff `json:"diff"`
+}
+
+func (server *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/up-apply", server.handleUpApply)
+	mux.HandleFunc("/down-apply", server.handleDownApply)
+	return mux
+}
+
+// requestContext bounds the operations applied for r, they stop as well when the
+// client goes away.
+func (server *server) requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	var timeout = server.requestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+func (server *server) handleUpApply(w http.ResponseWriter, r *http.Request) {
+	body, ok := server.readRequest(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := server.requestContext(r)
+	defer cancel()
+	var request upApplyRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "badRequest", err.Error())
+		return
+	}
+	if request.Message == nil {
+		writeError(w, http.StatusBadRequest, "badRequest", "'message' is required")
+		return
+	}
+	var upOperations operations.OperationsUpSerDer
+	if err := json.Unmarshal(body, &upOperations); err != nil {
+		writeMappingError(w, http.StatusBadRequest, "badRequest", err)
+		return
+	}
+	if isExplain(r) {
+		explanation, err := server.service.ExplainUpOperationsContext(ctx, request.Message, &upOperations)
+		if err != nil {
+			status, code := errorStatus(err)
+			writeJson(w, status, upExplainErrorResponse{UpExplanation: explanation, Error: errorInfo(code, err)})
+			return
+		}
+		writeJson(w, http.StatusAccepted, explanation)
+		return
+	}
+	retMessage, err := server.service.ApplyUpOperationsContext(ctx, request.Message, &upOperations)
+	if err != nil {
+		status, code := errorStatus(err)
+		writeMappingError(w, status, code, err)
+		return
+	}
+	if isDiff(r) {
+		writeJson(w, http.StatusAccepted, upDiffResponse{Message: retMessage, Diff: operations.DiffUpMessages(request.Message, retMessage)})
+		return
+	}
+	writeJson(w, http.StatusAccepted, retMessage)
+}
+
+func (server *server) handleDownApply(w http.ResponseWriter, r *http.Request) {
+	body, ok := server.readRequest(w, r)
+	if !ok {
+		return
+	}
+	ctx, cancel := server.requestContext(r)
+	defer cancel()
+	var request downApplyRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "badRequest", err.Error())
+		return
+	}
+	if request.Message == nil {
+		writeError(w, http.StatusBadRequest, "badRequest", "'message' is required")
+		return
+	}
+	// OperationsDownSerDer reads its operations from an "operations" field,
+	// the endpoint receives them as "operationsDown".
+	rawOperations, err := json.Marshal(map[string][]json.RawMessage{"operations": request.OperationsDown})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badRequest", err.Error())
+		return
+	}
+	var downOperations operations.OperationsDownSerDer
+	if err = json.Unmarshal(rawOperations, &downOperations); err != nil {
+		writeMappingError(w, http.StatusBadRequest, "badRequest", err)
+		return
+	}
+	if isExplain(r) {
+		explanation, err := server.service.ExplainDownOperationsContext(ctx, request.Message, &downOperations)
+		if err != nil {
+			status, code := errorStatus(err)
+			writeJson(w, status, downExplainErrorResponse{DownExplanation: explanation, Error: errorInfo(code, err)})
+			return
+		}
+		writeJson(w, http.StatusAccepted, explanation)
+		return
+	}
+	retMessage, err := server.service.ApplyDownOperationsContext(ctx, request.Message, &downOperations)
+	if err != nil {
+		status, code := errorStatus(err)
+		writeMappingError(w, status, code, err)
+		return
+	}
+	if isDiff(r) {
+		writeJson(w, http.StatusAccepted, downDiffResponse{Message: retMessage, Diff: operations.DiffDownMessages(request.Message, retMessage)})
+		return
+	}
+	writeJson(w, http.StatusAccepted, retMessage)
+}
+
+func (server *server) readRequest(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "methodNotAllowed", "only POST is supported")
+		return nil, false
+	}
+	if len(server.token) > 0 && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+server.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid bearer token")
+		return nil, false
+	}
+	var maxBodyBytes = server.maxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	// the reader stops with an error once the limit is read
+	if err != nil && int64(len(body)) >= maxBodyBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "requestTooLarge", fmt.Sprintf("the request body exceeds %d bytes", maxBodyBytes))
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "badRequest", err.Error())
+		return nil, false
+	}
+	return body, true
+}
+
+// isExplain tells whether the caller asked, with ?explain=true, for the step by
+// step trace of the operations instead of the transformed message.
+func isExplain(r *http.Request) bool {
+	return r.URL.Query().Get("explain") == "true"
+}
+
+// isDiff tells whether the caller asked, with ?diff=true, for what the operations
+// changed in the message along with the transformed message.
+func isDiff(r *http.Request) bool {
+	return r.URL.Query().Get("diff") == "true"
+}
+
+// errorStatus is the status and code answered when the operations fail: 403 for a
+// rejected command and 409 for any other failure, such as a passed deadline.
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, util.ErrCommandRejected):
+		return http.StatusForbidden, "forbidden"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusConflict, "deadlineExceeded"
+	}
+	return http.StatusConflict, "conflict"
+}
+
+// writeMappingError answers with the ErrorInfo of err when it is a util.MappingError,
+// so clients get the failing operation, key and expression, and with code otherwise.
+func writeMappingError(w http.ResponseWriter, status int, code string, err error) {
+	writeJson(w, status, errorInfo(code, err))
+}
+
+func errorInfo(code string, err error) flow.ErrorInfo {
+	var mappingError *util.MappingError
+	if errors.As(err, &mappingError) {
+		return mappingError.FlowErrorInfo()
+	}
+	return flow.ErrorInfo{Code: code, Message: err.Error()}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJson(w, status, flow.ErrorInfo{Code: code, Message: message})
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/operations"
+	"strings"
+	"testing"
+	"time"
+)
+
+var upApplyBody = `{
+  "message": {
+    "time": "2020-01-01T10:00:00Z",
+    "type": "deviceUplink",
+    "thing": {"key": "lora:0102030405060708"},
+    "packet": {"type": "", "message": {"temperature": 22.6}}
+  },
+  "operations": [{
+    "op": "extractPoints",
+    "points": {
+      "temperature": {"value": "{{packet.message.temperature}}", "eventTime": "{{time}}", "type": "double", "unitId": "Cel"}
+    }
+  }]
+}`
+
+var downApplyBody = `{
+  "message": {
+    "time": "2020-01-01T10:00:00Z",
+    "type": "deviceDownlink",
+    "command": {"id": "myDeviceCommand", "input": {"prop1": 10}}
+  },
+  "operationsDown": [{
+    "op": "updateCommand",
+    "commands": {"myDeviceCommand": {"id": "newCommandId"}}
+  }]
+}`
+
+func buildServer(token string) http.Handler {
+	var mappingServer = &server{service: operations.OperationService{Factory: operations.OperationFactory{}}, token: token}
+	return mappingServer.routes()
+}
+
+func doRequest(handler http.Handler, method string, path string, body string, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if len(token) > 0 {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func Test_should_apply_up_operations(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	// When
+	response := doRequest(handler, http.MethodPost, "/up-apply", upApplyBody, "")
+	// Then
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	var outputMessage flow.UpMessage
+	_ = json.Unmarshal(response.Body.Bytes(), &outputMessage)
+	assert.Equal(t, "Cel", outputMessage.Points["temperature"].UnitId)
+	assert.Equal(t, 22.6, outputMessage.Points["temperature"].Records[0].Value)
+}
+
+func Test_should_apply_down_operations(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	// When
+	response := doRequest(handler, http.MethodPost, "/down-apply", downApplyBody, "")
+	// Then
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	var outputMessage flow.DownMessage
+	_ = json.Unmarshal(response.Body.Bytes(), &outputMessage)
+	assert.Equal(t, "newCommandId", outputMessage.Command.Id)
+}
+
+func Test_should_return_forbidden_when_command_is_rejected(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	body := strings.Replace(downApplyBody, `"op": "updateCommand"`, `"op": "filter", "denyCommands": ["myDeviceCommand"]}, {"op": "updateCommand"`, 1)
+	// When
+	response := doRequest(handler, http.MethodPost, "/down-apply", body, "")
+	// Then
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	var errorInfo flow.ErrorInfo
+	_ = json.Unmarshal(response.Body.Bytes(), &errorInfo)
+	assert.Equal(t, "commandRejected", errorInfo.Code)
+	assert.Equal(t, "operation 0 'filter', key 'myDeviceCommand': command 'myDeviceCommand' is in 'denyCommands'", errorInfo.Message)
+}
+
+func Test_should_return_unauthorized_when_token_does_not_match(t *testing.T) {
+	// Given
+	handler := buildServer("secret")
+	// When
+	response := doRequest(handler, http.MethodPost, "/up-apply", upApplyBody, "other")
+	// Then
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	var errorInfo flow.ErrorInfo
+	_ = json.Unmarshal(response.Body.Bytes(), &errorInfo)
+	assert.Equal(t, "unauthorized", errorInfo.Code)
+}
+
+func Test_should_accept_matching_token(t *testing.T) {
+	// Given
+	handler := buildServer("secret")
+	// When
+	response := doRequest(handler, http.MethodPost, "/up-apply", upApplyBody, "secret")
+	// Then
+	assert.Equal(t, http.StatusAccepted, response.Code)
+}
+
+func Test_should_return_request_too_large_when_body_exceeds_limit(t *testing.T) {
+	// Given
+	var mappingServer = &server{service: operations.OperationService{Factory: operations.OperationFactory{}}, maxBodyBytes: 64}
+	// When
+	response := doRequest(mappingServer.routes(), http.MethodPost, "/up-apply", upApplyBody, "")
+	// Then
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	var errorInfo flow.ErrorInfo
+	_ = json.Unmarshal(response.Body.Bytes(), &errorInfo)
+	assert.Equal(t, "requestTooLarge", errorInfo.Code)
+}
+
+func Test_should_return_conflict_when_operation_fails(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	body := strings.Replace(upApplyBody, `"{{time}}"`, `"{{packet.message.unknown}}"`, 1)
+	body = strings.Replace(body, `"{{packet.message.temperature}}"`, `"{{[packet.message.temperature, packet.message.temperature]}}"`, 1)
+	// When
+	response := doRequest(handler, http.MethodPost, "/up-apply", body, "")
+	// Then
+	assert.Equal(t, http.StatusConflict, response.Code)
+	var errorInfo flow.ErrorInfo
+	_ = json.Unmarshal(response.Body.Bytes(), &errorInfo)
+	assert.Equal(t, "cardinalityMismatch", errorInfo.Code)
+	assert.Equal(t, "operation 0 'extractPoints', key 'temperature': there is a mismatch in cardinality for 'value' and 'eventTime' temperature", errorInfo.Message)
+}
+
+func Test_should_return_conflict_when_request_exceeds_its_time_budget(t *testing.T) {
+	// Given
+	var mappingServer = &server{service: operations.OperationService{Factory: operations.OperationFactory{}}, requestTimeout: time.Nanosecond}
+	// When
+	response := doRequest(mappingServer.routes(), http.MethodPost, "/up-apply", upApplyBody, "")
+	// Then
+	assert.Equal(t, http.StatusConflict, response.Code)
+	var errorInfo flow.ErrorInfo
+	_ = json.Unmarshal(response.Body.Bytes(), &errorInfo)
+	assert.Equal(t, "deadlineExceeded", errorInfo.Code)
+	assert.Equal(t, "operation 0 'extractPoints' exceeded the deadline: context deadline exceeded", errorInfo.Message)
+}
+
+func Test_should_return_bad_request_for_unknown_operation(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	body := strings.Replace(upApplyBody, `"extractPoints"`, `"unknown"`, 1)
+	// When
+	response := doRequest(handler, http.MethodPost, "/up-apply", body, "")
+	// Then
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	var errorInfo flow.ErrorInfo
+	_ = json.Unmarshal(response.Body.Bytes(), &errorInfo)
+	assert.Equal(t, "unknownOperation", errorInfo.Code)
+}
+
+func Test_should_reject_non_post_requests(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	// When
+	response := doRequest(handler, http.MethodGet, "/down-apply", "", "")
+	// Then
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+}
+
+func Test_should_explain_up_operations(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	// When
+	response := doRequest(handler, http.MethodPost, "/up-apply?explain=true", upApplyBody, "")
+	// Then
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	var explanation operations.UpExplanation
+	_ = json.Unmarshal(response.Body.Bytes(), &explanation)
+	assert.Equal(t, 1, len(explanation.Operations))
+	assert.Equal(t, "extractPoints", explanation.Operations[0].Op)
+	assert.Equal(t, "Cel", explanation.Message.Points["temperature"].UnitId)
+}
+
+func Test_should_return_conflict_with_explanation_when_operation_fails(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	body := strings.Replace(upApplyBody, `"{{time}}"`, `"{{packet.message.unknown}}"`, 1)
+	body = strings.Replace(body, `"{{packet.message.temperature}}"`, `"{{[packet.message.temperature, packet.message.temperature]}}"`, 1)
+	// When
+	response := doRequest(handler, http.MethodPost, "/up-apply?explain=true", body, "")
+	// Then
+	assert.Equal(t, http.StatusConflict, response.Code)
+	var explanation upExplainErrorResponse
+	_ = json.Unmarshal(response.Body.Bytes(), &explanation)
+	assert.Equal(t, 1, len(explanation.Operations))
+	assert.NotEmpty(t, explanation.Operations[0].Error)
+	assert.Equal(t, "cardinalityMismatch", explanation.Error.Code)
+}
+
+func Test_should_return_diff_of_down_message(t *testing.T) {
+	// Given
+	handler := buildServer("")
+	// When
+	response := doRequest(handler, http.MethodPost, "/down-apply?diff=true", downApplyBody, "")
+	// Then
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	var diffResponse downDiffResponse
+	_ = json.Unmarshal(response.Body.Bytes(), &diffResponse)
+	assert.Equal(t, "newCommandId", diffResponse.Message.Command.Id)
+	assert.Equal(t, []operations.FieldChange{{Field: "id", Before: "myDeviceCommand", After: "newCommandId"}}, diffResponse.Diff.Command)
+}
@@ -0,0 +1,19 @@
+package ontology
+
+// CoordinateFormat tells how the longitude and latitude of a point are written.
+type CoordinateFormat string
+
+const (
+	DECIMAL_CoordinateFormat CoordinateFormat = "decimal"
+	DMS_CoordinateFormat     CoordinateFormat = "dms"
+	NMEA_CoordinateFormat    CoordinateFormat = "nmea"
+	SCALED_CoordinateFormat  CoordinateFormat = "scaled"
+)
+
+// CoordinateOrder tells which of the first two coordinate expressions is the longitude.
+type CoordinateOrder string
+
+const (
+	LNG_LAT_CoordinateOrder CoordinateOrder = "lngLat"
+	LAT_LNG_CoordinateOrder CoordinateOrder = "latLng"
+)
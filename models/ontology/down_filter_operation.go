@@ -0,0 +1,19 @@
+package ontology
+
+// DownFilterOperation rejects the commands that may not be sent. A command passes
+// when its id is in AllowCommands, if set, and not in DenyCommands, the origin
+// type of the message is in OriginTypes, if set, and the Thing and SubAccount
+// predicates, JMESPath templates evaluated on the thing and on the sub-account of
+// the message, hold when set.
+type DownFilterOperation struct {
+	AllowCommands []string `json:"allowCommands,omitempty"`
+	DenyCommands  []string `json:"denyCommands,omitempty"`
+	OriginTypes   []string `json:"originTypes,omitempty"`
+	Thing         string   `json:"thing,omitempty"`
+	SubAccount    string   `json:"subAccount,omitempty"`
+	DownOperation
+}
+
+func (filter DownFilterOperation) ValidDownOperation() string {
+	return "filter"
+}
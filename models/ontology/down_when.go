@@ -0,0 +1,55 @@
+package ontology
+
+import "encoding/json"
+
+// DownWhen is the down equivalent of UpWhen.
+type DownWhen struct {
+	Predicate string                   `json:"predicate"`
+	Then      []DownOperationInterface `json:"-"`
+	Else      []DownOperationInterface `json:"-"`
+	RawThen   []json.RawMessage        `json:"then"`
+	RawElse   []json.RawMessage        `json:"else,omitempty"`
+	DownOperation
+}
+
+func (when DownWhen) ValidDownOperation() string {
+	return "when"
+}
+
+// DecodeDownOperations decodes the operations of Then and Else.
+func (when *DownWhen) DecodeDownOperations(decode func(field string, raws []json.RawMessage) ([]DownOperationInterface, error)) error {
+	var err error
+	if when.Then, err = decode("then", when.RawThen); err != nil {
+		return err
+	}
+	when.Else, err = decode("else", when.RawElse)
+	return err
+}
+
+func (when DownWhen) MarshalJSON() ([]byte, error) {
+	type downWhen DownWhen
+	var err error
+	if when.Then != nil {
+		if when.RawThen, err = marshalDownOperations(when.Then); err != nil {
+			return nil, err
+		}
+	}
+	if when.Else != nil {
+		if when.RawElse, err = marshalDownOperations(when.Else); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(downWhen(when))
+}
+
+func marshalDownOperations(operations []DownOperationInterface) ([]json.RawMessage, error) {
+	var raws []json.RawMessage
+	for _, operation := range operations {
+		raw, err := json.Marshal(operation)
+		if err != nil {
+			return nil, err
+		}
+		raws = append(raws, raw)
+	}
+	return raws, nil
+}
@@ -0,0 +1,19 @@
+package ontology
+
+// EventTimeFormat tells how the eventTime of a point is written, one of the constants or a Go time layout.
+type EventTimeFormat string
+
+const (
+	RFC3339_EventTimeFormat  EventTimeFormat = "rfc3339"
+	EPOCH_S_EventTimeFormat  EventTimeFormat = "epoch_s"
+	EPOCH_MS_EventTimeFormat EventTimeFormat = "epoch_ms"
+	GPS_EventTimeFormat      EventTimeFormat = "gps"
+)
+
+// SampleDirection tells whether the samples of a point follow or precede their base time.
+type SampleDirection string
+
+const (
+	FORWARD_SampleDirection  SampleDirection = "forward"
+	BACKWARD_SampleDirection SampleDirection = "backward"
+)
@@ -1,10 +1,17 @@
 package ontology
 
 type JmesPathPoint struct {
-	OntologyId  string            `json:"ontologyId,omitempty"`
-	Value       string            `json:"value,omitempty"`
-	Coordinates []string          `json:"coordinates,omitempty"`
-	EventTime   string            `json:"eventTime"`
-	Type_       JmesPathPointType `json:"type,omitempty"`
-	UnitId      string            `json:"unitId,omitempty"`
+	OntologyId       string            `json:"ontologyId,omitempty"`
+	Value            string            `json:"value,omitempty"`
+	Coordinates      []string          `json:"coordinates,omitempty"`
+	CoordinateOrder  CoordinateOrder   `json:"coordinateOrder,omitempty"`
+	CoordinateFormat CoordinateFormat  `json:"coordinateFormat,omitempty"`
+	CoordinateScale  float64           `json:"coordinateScale,omitempty"`
+	EventTime        string            `json:"eventTime"`
+	EventTimeFormat  EventTimeFormat   `json:"eventTimeFormat,omitempty"`
+	Timezone         string            `json:"timezone,omitempty"`
+	SamplePeriod     string            `json:"samplePeriod,omitempty"`
+	SampleDirection  SampleDirection   `json:"sampleDirection,omitempty"`
+	Type_            JmesPathPointType `json:"type,omitempty"`
+	UnitId           string            `json:"unitId,omitempty"`
 }
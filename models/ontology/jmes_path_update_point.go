@@ -1,10 +1,12 @@
 package ontology
 
 type JmesPathUpdatePoint struct {
-	OntologyId  string            `json:"ontologyId,omitempty"`
-	Value       string            `json:"value,omitempty"`
-	Coordinates []string          `json:"coordinates,omitempty"`
-	EventTime   string            `json:"eventTime,omitempty"`
-	Type_       JmesPathPointType `json:"type,omitempty"`
-	UnitId      string            `json:"unitId,omitempty"`
+	OntologyId      string            `json:"ontologyId,omitempty"`
+	Value           string            `json:"value,omitempty"`
+	Coordinates     []string          `json:"coordinates,omitempty"`
+	EventTime       string            `json:"eventTime,omitempty"`
+	EventTimeFormat EventTimeFormat   `json:"eventTimeFormat,omitempty"`
+	Timezone        string            `json:"timezone,omitempty"`
+	Type_           JmesPathPointType `json:"type,omitempty"`
+	UnitId          string            `json:"unitId,omitempty"`
 }
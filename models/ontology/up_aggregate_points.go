@@ -0,0 +1,43 @@
+package ontology
+
+type AggregateFunction string
+
+const (
+	MIN_AggregateFunction        AggregateFunction = "min"
+	MAX_AggregateFunction        AggregateFunction = "max"
+	MEAN_AggregateFunction       AggregateFunction = "mean"
+	SUM_AggregateFunction        AggregateFunction = "sum"
+	COUNT_AggregateFunction      AggregateFunction = "count"
+	FIRST_AggregateFunction      AggregateFunction = "first"
+	LAST_AggregateFunction       AggregateFunction = "last"
+	PERCENTILE_AggregateFunction AggregateFunction = "percentile"
+)
+
+type AggregateEventTime string
+
+const (
+	FIRST_AggregateEventTime   AggregateEventTime = "first"
+	LAST_AggregateEventTime    AggregateEventTime = "last"
+	MESSAGE_AggregateEventTime AggregateEventTime = "message"
+)
+
+// AggregatePoint derives a point with a single record from the records of the
+// Source point. EventTime defaults to the last eventTime of the source records and
+// UnitId to the unitId of the source, but for count.
+type AggregatePoint struct {
+	Source     string             `json:"source"`
+	Function   AggregateFunction  `json:"function"`
+	Percentile float64            `json:"percentile,omitempty"`
+	EventTime  AggregateEventTime `json:"eventTime,omitempty"`
+	OntologyId string             `json:"ontologyId,omitempty"`
+	UnitId     string             `json:"unitId,omitempty"`
+}
+
+type UpAggregatePoints struct {
+	Points map[string]AggregatePoint `json:"points"`
+	UpOperation
+}
+
+func (aggregatePoints UpAggregatePoints) ValidUpOperation() string {
+	return "aggregatePoints"
+}
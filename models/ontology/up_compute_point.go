@@ -0,0 +1,16 @@
+package ontology
+
+// UpComputePoint writes Point from an arithmetic Expression over the values of
+// other points, such as "temperature - (100 - humidity) / 5".
+type UpComputePoint struct {
+	Point      string            `json:"point"`
+	Expression string            `json:"expression"`
+	Type_      JmesPathPointType `json:"type,omitempty"`
+	OntologyId string            `json:"ontologyId,omitempty"`
+	UnitId     string            `json:"unitId,omitempty"`
+	UpOperation
+}
+
+func (computePoint UpComputePoint) ValidUpOperation() string {
+	return "computePoint"
+}
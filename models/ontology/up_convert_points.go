@@ -0,0 +1,10 @@
+package ontology
+
+type UpConvertPoints struct {
+	Points []string `json:"points,omitempty"`
+	UpOperation
+}
+
+func (convertPoints UpConvertPoints) ValidUpOperation() string {
+	return "convertPoints"
+}
@@ -0,0 +1,11 @@
+package ontology
+
+type UpConvertUnits struct {
+	// Points gives the target unitId of each point to convert.
+	Points map[string]string `json:"points"`
+	UpOperation
+}
+
+func (convertUnits UpConvertUnits) ValidUpOperation() string {
+	return "convertUnits"
+}
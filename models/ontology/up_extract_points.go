@@ -2,6 +2,8 @@ package ontology
 
 type UpExtractPoints struct {
 	Points map[string]JmesPathPoint `json:"points"`
+	// ConvertValues coerces the values of the points to their declared type.
+	ConvertValues bool `json:"convertValues,omitempty"`
 	UpOperation
 }
 
@@ -1,11 +1,27 @@
 package ontology
 
+// FilterMode tells whether the messages matching a filter predicate are kept or
+// dropped.
+type FilterMode string
+
+const (
+	ALLOW_FilterMode FilterMode = "allow"
+	DENY_FilterMode  FilterMode = "deny"
+)
+
+// UpFilterOperation keeps the messages whose type is flagged by the Keep* fields.
+// When Predicate is set, a JMESPath template evaluated on the message, the kept
+// messages are further restricted to those on which it holds in allow Mode, the
+// default, or to those on which it does not in deny Mode. A predicate without
+// any Keep* flag set applies to every message type.
 type UpFilterOperation struct {
-	KeepDeviceUplink               bool     `json:"keepDeviceUplink,omitempty"`
-	KeepDeviceDownlinkSent         bool     `json:"keepDeviceDownlinkSent,omitempty"`
-	KeepDeviceLocation             bool     `json:"keepDeviceLocation,omitempty"`
-	KeepDeviceNotification         bool     `json:"keepDeviceNotification,omitempty"`
-	KeepDeviceNotificationSubTypes []string `json:"keepDeviceNotificationSubTypes,omitempty"`
+	KeepDeviceUplink               bool       `json:"keepDeviceUplink,omitempty"`
+	KeepDeviceDownlinkSent         bool       `json:"keepDeviceDownlinkSent,omitempty"`
+	KeepDeviceLocation             bool       `json:"keepDeviceLocation,omitempty"`
+	KeepDeviceNotification         bool       `json:"keepDeviceNotification,omitempty"`
+	KeepDeviceNotificationSubTypes []string   `json:"keepDeviceNotificationSubTypes,omitempty"`
+	Predicate                      string     `json:"predicate,omitempty"`
+	Mode                           FilterMode `json:"mode,omitempty"`
 	UpOperation
 }
 
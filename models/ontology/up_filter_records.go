@@ -0,0 +1,25 @@
+package ontology
+
+// EventTimeWindow bounds eventTimes relative to the message time with durations
+// such as "-24h" or "5m", an empty bound is open.
+type EventTimeWindow struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// UpFilterRecords keeps the records of Points, or of every point when empty, that
+// satisfy all the predicates set. Expression is a JMESPath template evaluated on
+// {"point", "value", "eventTime", "coordinates"} that must return a boolean.
+type UpFilterRecords struct {
+	Points         []string         `json:"points,omitempty"`
+	Min            *float64         `json:"min,omitempty"`
+	Max            *float64         `json:"max,omitempty"`
+	Expression     string           `json:"expression,omitempty"`
+	EventTime      *EventTimeWindow `json:"eventTime,omitempty"`
+	HasCoordinates *bool            `json:"hasCoordinates,omitempty"`
+	UpOperation
+}
+
+func (filterRecords UpFilterRecords) ValidUpOperation() string {
+	return "filterRecords"
+}
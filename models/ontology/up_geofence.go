@@ -0,0 +1,32 @@
+package ontology
+
+import "encoding/json"
+
+// GeofenceZone is either a polygon, given as GeoJSON Polygon coordinates where
+// the first ring bounds the zone and the next ones are holes, or a circle of
+// Radius meters around Center. Positions are [longitude, latitude].
+type GeofenceZone struct {
+	Name    string        `json:"name"`
+	Polygon [][][]float64 `json:"polygon,omitempty"`
+	Center  []float64     `json:"center,omitempty"`
+	Radius  float64       `json:"radius,omitempty"`
+}
+
+// UpGeofence locates every coordinate record of Points, or of every point holding
+// coordinates when empty, in Zones and in the zones of the GeoJSON FeatureCollection
+// or Feature GeoJson. It emits a boolean InsideZonePoint record and, for records
+// inside a zone, a ZoneNamePoint record naming the first zone containing it. When
+// a record is inside a zone SubType, if set, becomes the subType of the message.
+type UpGeofence struct {
+	Points          []string        `json:"points,omitempty"`
+	Zones           []GeofenceZone  `json:"zones,omitempty"`
+	GeoJson         json.RawMessage `json:"geoJson,omitempty"`
+	InsideZonePoint string          `json:"insideZonePoint,omitempty"`
+	ZoneNamePoint   string          `json:"zoneNamePoint,omitempty"`
+	SubType         string          `json:"subType,omitempty"`
+	UpOperation
+}
+
+func (geofence UpGeofence) ValidUpOperation() string {
+	return "geofence"
+}
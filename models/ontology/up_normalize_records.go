@@ -0,0 +1,24 @@
+package ontology
+
+type DuplicateStrategy string
+
+const (
+	FIRST_DuplicateStrategy DuplicateStrategy = "first"
+	LAST_DuplicateStrategy  DuplicateStrategy = "last"
+	ERROR_DuplicateStrategy DuplicateStrategy = "error"
+)
+
+// UpNormalizeRecords sorts the records of Points, or of every point when empty, by
+// eventTime. Records sharing an eventTime are collapsed according to Duplicates,
+// and kept when it is empty, then only the KeepLatest latest records are kept
+// when it is set.
+type UpNormalizeRecords struct {
+	Points     []string          `json:"points,omitempty"`
+	Duplicates DuplicateStrategy `json:"duplicates,omitempty"`
+	KeepLatest int               `json:"keepLatest,omitempty"`
+	UpOperation
+}
+
+func (normalizeRecords UpNormalizeRecords) ValidUpOperation() string {
+	return "normalizeRecords"
+}
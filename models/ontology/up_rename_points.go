@@ -0,0 +1,30 @@
+package ontology
+
+type PointKeyCase string
+
+const (
+	SNAKE_TO_CAMEL_PointKeyCase PointKeyCase = "snakeToCamel"
+	CAMEL_TO_SNAKE_PointKeyCase PointKeyCase = "camelToSnake"
+)
+
+// RenamePointsRule renames the keys matching Match, or every key when Match is
+// empty: Match is replaced by Replace when it is set, possibly to the empty string
+// to strip it, then Case, Prefix and Suffix are applied.
+type RenamePointsRule struct {
+	Match   string       `json:"match,omitempty"`
+	Replace *string      `json:"replace,omitempty"`
+	Case    PointKeyCase `json:"case,omitempty"`
+	Prefix  string       `json:"prefix,omitempty"`
+	Suffix  string       `json:"suffix,omitempty"`
+}
+
+type UpRenamePoints struct {
+	// Points renames keys explicitly, these keys are not submitted to Rules.
+	Points map[string]string  `json:"points,omitempty"`
+	Rules  []RenamePointsRule `json:"rules,omitempty"`
+	UpOperation
+}
+
+func (renamePoints UpRenamePoints) ValidUpOperation() string {
+	return "renamePoints"
+}
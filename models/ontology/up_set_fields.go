@@ -0,0 +1,14 @@
+package ontology
+
+// UpSetFields writes values into the envelope of the message. Fields maps a
+// target, a JSON pointer such as "/subType", "/thing/tags/-" or
+// "/content/site/name", to a constant or to a "{{ }}" template evaluated on the
+// message. Targets are set in their lexical order.
+type UpSetFields struct {
+	Fields map[string]interface{} `json:"fields"`
+	UpOperation
+}
+
+func (setFields UpSetFields) ValidUpOperation() string {
+	return "setFields"
+}
@@ -0,0 +1,56 @@
+package ontology
+
+import "encoding/json"
+
+// UpWhen applies Then when the JMESPath Predicate holds on the message, Else
+// otherwise. A predicate holds unless it returns false, null or an empty value.
+type UpWhen struct {
+	Predicate string                 `json:"predicate"`
+	Then      []UpOperationInterface `json:"-"`
+	Else      []UpOperationInterface `json:"-"`
+	RawThen   []json.RawMessage      `json:"then"`
+	RawElse   []json.RawMessage      `json:"else,omitempty"`
+	UpOperation
+}
+
+func (when UpWhen) ValidUpOperation() string {
+	return "when"
+}
+
+// DecodeUpOperations decodes the operations of Then and Else.
+func (when *UpWhen) DecodeUpOperations(decode func(field string, raws []json.RawMessage) ([]UpOperationInterface, error)) error {
+	var err error
+	if when.Then, err = decode("then", when.RawThen); err != nil {
+		return err
+	}
+	when.Else, err = decode("else", when.RawElse)
+	return err
+}
+
+func (when UpWhen) MarshalJSON() ([]byte, error) {
+	type upWhen UpWhen
+	var err error
+	if when.Then != nil {
+		if when.RawThen, err = marshalUpOperations(when.Then); err != nil {
+			return nil, err
+		}
+	}
+	if when.Else != nil {
+		if when.RawElse, err = marshalUpOperations(when.Else); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(upWhen(when))
+}
+
+func marshalUpOperations(operations []UpOperationInterface) ([]json.RawMessage, error) {
+	var raws []json.RawMessage
+	for _, operation := range operations {
+		raw, err := json.Marshal(operation)
+		if err != nil {
+			return nil, err
+		}
+		raws = append(raws, raw)
+	}
+	return raws, nil
+}
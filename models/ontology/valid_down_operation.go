@@ -1,5 +1,12 @@
 package ontology
 
+import "encoding/json"
+
 type DownOperationInterface interface {
 	ValidDownOperation() string
 }
+
+// DownOperationsDecoder is the down equivalent of UpOperationsDecoder.
+type DownOperationsDecoder interface {
+	DecodeDownOperations(decode func(field string, raws []json.RawMessage) ([]DownOperationInterface, error)) error
+}
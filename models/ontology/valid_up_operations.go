@@ -1,5 +1,14 @@
 package ontology
 
+import "encoding/json"
+
 type UpOperationInterface interface {
 	ValidUpOperation() string
 }
+
+// UpOperationsDecoder is implemented by models nesting up operations, such as
+// *UpWhen. Once the model is unmarshalled, DecodeUpOperations is given decode to
+// turn the raw operations of each of its fields into operations.
+type UpOperationsDecoder interface {
+	DecodeUpOperations(decode func(field string, raws []json.RawMessage) ([]UpOperationInterface, error)) error
+}
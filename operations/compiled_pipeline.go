@@ -0,0 +1,143 @@
+package operations
+
+import (
+	"context"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"reflect"
+	"time"
+)
+
+// CompiledUpPipeline is an up mapping whose handlers are built and whose
+// JMESPath expressions are compiled once, to be applied to many messages. The
+// compiled expressions and rules reach the handlers through the context.
+type CompiledUpPipeline struct {
+	operations       []ontology.UpOperationInterface
+	handlers         []OperationHandler
+	expressions      util.CompiledExpressions
+	rules            *compiledRules
+	factory          OperationFactory
+	operationTimeout time.Duration
+	observer         Observer
+}
+
+// CompiledDownPipeline is the down equivalent of CompiledUpPipeline.
+type CompiledDownPipeline struct {
+	operations       []ontology.DownOperationInterface
+	handlers         []OperationHandler
+	expressions      util.CompiledExpressions
+	rules            *compiledRules
+	factory          OperationFactory
+	operationTimeout time.Duration
+	observer         Observer
+}
+
+func (operationService *OperationService) CompileUpOperations(operations *OperationsUpSerDer) (*CompiledUpPipeline, error) {
+	var pipeline = &CompiledUpPipeline{expressions: make(util.CompiledExpressions), rules: new(compiledRules), factory: operationService.Factory, operationTimeout: operationService.OperationTimeout, observer: operationService.Observer}
+	for index, operation := range operations.Operations {
+		handler, err := operationService.Factory.BuildUp(operation)
+		if err != nil {
+			return nil, util.WithOperation(err, index, operation.ValidUpOperation())
+		}
+		for _, expression := range upOperationExpressions(handler, operation) {
+			if err = pipeline.expressions.Compile(expression); err != nil {
+				return nil, &util.MappingError{Code: util.INVALID_EXPRESSION_ErrorCode, Index: index, Op: operation.ValidUpOperation(), Expression: expression, Message: err.Error(), Err: err}
+			}
+		}
+		pipeline.operations = append(pipeline.operations, operation)
+		pipeline.handlers = append(pipeline.handlers, handler)
+	}
+	return pipeline, nil
+}
+
+func (operationService *OperationService) CompileDownOperations(operations *OperationsDownSerDer) (*CompiledDownPipeline, error) {
+	var pipeline = &CompiledDownPipeline{expressions: make(util.CompiledExpressions), rules: new(compiledRules), factory: operationService.Factory, operationTimeout: operationService.OperationTimeout, observer: operationService.Observer}
+	for index, operation := range operations.Operations {
+		handler, err := operationService.Factory.BuildDown(operation)
+		if err != nil {
+			return nil, util.WithOperation(err, index, operation.ValidDownOperation())
+		}
+		for _, expression := range downOperationExpressions(handler, operation) {
+			if err = pipeline.expressions.Compile(expression); err != nil {
+				return nil, &util.MappingError{Code: util.INVALID_EXPRESSION_ErrorCode, Index: index, Op: operation.ValidDownOperation(), Expression: expression, Message: err.Error(), Err: err}
+			}
+		}
+		pipeline.operations = append(pipeline.operations, operation)
+		pipeline.handlers = append(pipeline.handlers, handler)
+	}
+	return pipeline, nil
+}
+
+func (pipeline *CompiledUpPipeline) Apply(message *flow.UpMessage) (*flow.UpMessage, error) {
+	return pipeline.ApplyContext(context.Background(), message)
+}
+
+func (pipeline *CompiledUpPipeline) ApplyContext(ctx context.Context, message *flow.UpMessage) (*flow.UpMessage, error) {
+	ctx = util.WithCompiledExpressions(ctx, pipeline.expressions)
+	ctx = withCompiledRules(ctx, pipeline.rules)
+	ctx = withOperationScope(ctx, pipeline.factory, pipeline.observer)
+	var err error
+	var retMessage = message
+	for i := range pipeline.operations {
+		if retMessage == nil {
+			return nil, nil
+		}
+		retMessage, err = applyUpOperation(ctx, pipeline.operationTimeout, pipeline.observer, i, pipeline.handlers[i], retMessage, &pipeline.operations[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return retMessage, nil
+}
+
+func (pipeline *CompiledDownPipeline) Apply(message *flow.DownMessage) (*flow.DownMessage, error) {
+	return pipeline.ApplyContext(context.Background(), message)
+}
+
+func (pipeline *CompiledDownPipeline) ApplyContext(ctx context.Context, message *flow.DownMessage) (*flow.DownMessage, error) {
+	ctx = util.WithCompiledExpressions(ctx, pipeline.expressions)
+	ctx = withCompiledRules(ctx, pipeline.rules)
+	ctx = withOperationScope(ctx, pipeline.factory, pipeline.observer)
+	var err error
+	var retMessage = message
+	for i := range pipeline.operations {
+		if retMessage == nil {
+			return nil, nil
+		}
+		retMessage, err = applyDownOperation(ctx, pipeline.operationTimeout, pipeline.observer, i, pipeline.handlers[i], retMessage, &pipeline.operations[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return retMessage, nil
+}
+
+func upOperationExpressions(handler OperationHandler, operation ontology.UpOperationInterface) []string {
+	if lister, ok := handler.(ExpressionLister); ok {
+		return lister.UpExpressions(operation)
+	}
+	return nil
+}
+
+func downOperationExpressions(handler OperationHandler, operation ontology.DownOperationInterface) []string {
+	if lister, ok := handler.(ExpressionLister); ok {
+		return lister.DownExpressions(operation)
+	}
+	return nil
+}
+
+func collectExpressions(node interface{}, expressions []string) []string {
+	if node == nil {
+		return expressions
+	}
+	if reflect.TypeOf(node).Kind() == reflect.String {
+		return append(expressions, node.(string))
+	}
+	if fields, ok := node.(map[string]interface{}); ok {
+		for _, element := range fields {
+			expressions = collectExpressions(element, expressions)
+		}
+	}
+	return expressions
+}
@@ -0,0 +1,117 @@
+package operations
+
+import (
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"testing"
+)
+
+var pipelineService = OperationService{Factory: OperationFactory{}}
+
+func buildUpOperationsTemperature(value string) *OperationsUpSerDer {
+	var upOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"temperature": {
+			Value:     value,
+			EventTime: "{{time}}",
+			Type_:     "double",
+			UnitId:    "Cel",
+		},
+	}}
+	return &OperationsUpSerDer{Operations: []ontology.UpOperationInterface{upOpr}}
+}
+
+func Test_should_apply_compiled_up_pipeline_like_operation_service(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	pipeline, err := pipelineService.CompileUpOperations(operations)
+	assert.Nil(t, err)
+	// When
+	for i := 0; i < 3; i++ {
+		inputUpMessage := buildInputUpMessage("include_existing_points.json")
+		outputUpMessage, err := pipeline.Apply(&inputUpMessage)
+		// Then
+		assert.Nil(t, err)
+		expectedUpMessage, _ := pipelineService.ApplyUpOperations(&inputUpMessage, operations)
+		assert.Equal(t, expectedUpMessage, outputUpMessage)
+		assert.Equal(t, 22.6, outputUpMessage.Points["temperature"].Records[0].Value)
+	}
+}
+
+func Test_should_hold_compiled_expressions_in_the_pipeline(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	// When
+	pipeline, err := pipelineService.CompileUpOperations(operations)
+	otherPipeline, otherErr := pipelineService.CompileUpOperations(buildUpOperationsTemperature("{{packet.message.humidity}}"))
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, otherErr)
+	assert.Equal(t, 2, len(pipeline.expressions))
+	assert.Contains(t, pipeline.expressions, "{{packet.message.temperature}}")
+	assert.Contains(t, pipeline.expressions, "{{time}}")
+	assert.NotContains(t, otherPipeline.expressions, "{{packet.message.temperature}}")
+}
+
+func Test_should_hold_compiled_rules_in_the_pipeline(t *testing.T) {
+	// Given
+	var strip = ""
+	var renameOpr ontology.UpOperationInterface = ontology.UpRenamePoints{Rules: []ontology.RenamePointsRule{
+		{Match: "^sensor_", Replace: &strip},
+	}}
+	pipeline, err := pipelineService.CompileUpOperations(&OperationsUpSerDer{Operations: []ontology.UpOperationInterface{renameOpr}})
+	assert.Nil(t, err)
+	// When
+	for i := 0; i < 3; i++ {
+		inputUpMessage := buildUpMessageWithPoint("sensor_temperature", flow.DOUBLE_Type, 22.5)
+		outputUpMessage, err := pipeline.Apply(&inputUpMessage)
+		// Then
+		assert.Nil(t, err)
+		assert.Contains(t, outputUpMessage.Points, "temperature")
+	}
+	var rules int
+	pipeline.rules.rules.Range(func(key, value interface{}) bool {
+		rules++
+		return true
+	})
+	assert.Equal(t, 1, rules)
+}
+
+func Test_should_fail_to_compile_up_pipeline_with_invalid_expression(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.[temperature}}")
+	// When
+	pipeline, err := pipelineService.CompileUpOperations(operations)
+	// Then
+	assert.Nil(t, pipeline)
+	assert.NotNil(t, err)
+}
+
+func Test_should_stop_compiled_up_pipeline_when_message_is_filtered(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceLocation: true}
+	operations.Operations = append([]ontology.UpOperationInterface{filterOpr}, operations.Operations...)
+	pipeline, _ := pipelineService.CompileUpOperations(operations)
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	outputUpMessage, err := pipeline.Apply(&inputUpMessage)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, outputUpMessage)
+}
+
+func Test_should_apply_compiled_down_pipeline(t *testing.T) {
+	// Given
+	var downOpr ontology.DownOperationInterface = ontology.DownUpdateCommand{Commands: map[string]ontology.UpdateCommand{
+		"myDeviceCommand": {Id: "newCommandId", Input: map[string]interface{}{"value": "{{input.prop1}}"}},
+	}}
+	pipeline, err := pipelineService.CompileDownOperations(&OperationsDownSerDer{Operations: []ontology.DownOperationInterface{downOpr}})
+	assert.Nil(t, err)
+	inputDownMessage := buildInputDownMessage("update_command_id.json")
+	// When
+	outputDownMessage, err := pipeline.Apply(&inputDownMessage)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, &flow.Command{Id: "newCommandId", Input: map[string]interface{}{"value": 10.0}}, outputDownMessage.Command)
+}
@@ -0,0 +1,45 @@
+package operations
+
+import (
+	"context"
+	"sync"
+)
+
+// compiledRules holds what operations build from their rules, such as patterns or
+// expressions, for the lifetime of a compiled pipeline. Handlers are built anew
+// on every apply outside a pipeline, so they cannot keep it themselves. It is
+// shared by the messages the pipeline maps concurrently.
+type compiledRules struct {
+	rules sync.Map
+}
+
+type compiledRuleKey struct {
+	kind   string
+	source string
+}
+
+type compiledRulesKey struct{}
+
+func withCompiledRules(ctx context.Context, rules *compiledRules) context.Context {
+	return context.WithValue(ctx, compiledRulesKey{}, rules)
+}
+
+// compiledRule returns what compile builds from the source of the given kind,
+// compiled once per pipeline and once per apply without one. Failures are not
+// kept, they are reported again by every apply.
+func compiledRule(ctx context.Context, kind string, source string, compile func() (interface{}, error)) (interface{}, error) {
+	rules, _ := ctx.Value(compiledRulesKey{}).(*compiledRules)
+	if rules == nil {
+		return compile()
+	}
+	var key = compiledRuleKey{kind: kind, source: source}
+	if rule, ok := rules.rules.Load(key); ok {
+		return rule, nil
+	}
+	rule, err := compile()
+	if err != nil {
+		return nil, err
+	}
+	rule, _ = rules.rules.LoadOrStore(key, rule)
+	return rule, nil
+}
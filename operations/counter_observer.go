@@ -0,0 +1,111 @@
+package operations
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OperationsStartedMetric        = "ontology_mapping_operations_started_total"
+	OperationDurationSecondsMetric = "ontology_mapping_operation_duration_seconds_sum"
+	OperationsEndedMetric          = "ontology_mapping_operation_duration_seconds_count"
+	MessagesDroppedMetric          = "ontology_mapping_messages_dropped_total"
+	PointsProducedMetric           = "ontology_mapping_points_produced_total"
+	RecordsProducedMetric          = "ontology_mapping_records_produced_total"
+	OperationErrorsMetric          = "ontology_mapping_operation_errors_total"
+)
+
+// CounterObserver is an Observer keeping Prometheus-style counters in memory,
+// labelled by mapping, direction and op, and by point or error code where it applies.
+type CounterObserver struct {
+	mutex    sync.Mutex
+	counters map[string]float64
+}
+
+func NewCounterObserver() *CounterObserver {
+	return &CounterObserver{counters: make(map[string]float64)}
+}
+
+func (observer *CounterObserver) OperationStarted(event OperationEvent) {
+	observer.add(OperationsStartedMetric, eventLabels(event), 1)
+}
+
+func (observer *CounterObserver) OperationEnded(event OperationEvent, duration time.Duration) {
+	observer.add(OperationDurationSecondsMetric, eventLabels(event), duration.Seconds())
+	observer.add(OperationsEndedMetric, eventLabels(event), 1)
+}
+
+func (observer *CounterObserver) MessageDropped(event OperationEvent) {
+	observer.add(MessagesDroppedMetric, eventLabels(event), 1)
+}
+
+func (observer *CounterObserver) PointsProduced(event OperationEvent, recordsPerPoint map[string]int) {
+	for point, records := range recordsPerPoint {
+		var labels = eventLabels(event)
+		labels["point"] = point
+		observer.add(PointsProducedMetric, labels, 1)
+		observer.add(RecordsProducedMetric, labels, float64(records))
+	}
+}
+
+func (observer *CounterObserver) OperationFailed(event OperationEvent, err error) {
+	var labels = eventLabels(event)
+	labels["code"] = errorCode(err)
+	observer.add(OperationErrorsMetric, labels, 1)
+}
+
+// Counter returns the value of the counter name with exactly the given labels.
+func (observer *CounterObserver) Counter(name string, labels map[string]string) float64 {
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	return observer.counters[seriesName(name, labels)]
+}
+
+// WriteTo writes every counter in the Prometheus text exposition format.
+func (observer *CounterObserver) WriteTo(w io.Writer) (int64, error) {
+	observer.mutex.Lock()
+	var series []string
+	for name, value := range observer.counters {
+		series = append(series, name+" "+strconv.FormatFloat(value, 'g', -1, 64)+"\n")
+	}
+	observer.mutex.Unlock()
+	sort.Strings(series)
+	var written int64
+	for _, line := range series {
+		n, err := io.WriteString(w, line)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (observer *CounterObserver) add(name string, labels map[string]string, value float64) {
+	observer.mutex.Lock()
+	observer.counters[seriesName(name, labels)] += value
+	observer.mutex.Unlock()
+}
+
+func eventLabels(event OperationEvent) map[string]string {
+	return map[string]string{"mapping": event.Mapping, "direction": string(event.Direction), "op": event.Op}
+}
+
+// seriesName renders a series as name{label="value",...} with sorted labels.
+func seriesName(name string, labels map[string]string) string {
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, labels[key]))
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"ontology-mapping-go-lib/models/flow"
 )
 import "ontology-mapping-go-lib/models/ontology"
@@ -9,29 +10,42 @@ import "ontology-mapping-go-lib/util"
 type DownExtractDriverOperation struct {
 }
 
+func init() {
+	MustRegisterDownOperation("extractDriverMessage", func() ontology.DownOperationInterface { return &ontology.DownExtractDriverMessage{} }, func() OperationHandler { return &DownExtractDriverOperation{} })
+}
+
 func (downExtractDriver *DownExtractDriverOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
 	return nil, nil
 }
 
+func (downExtractDriver *DownExtractDriverOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return nil, nil
+}
+
 func (downExtractDriver *DownExtractDriverOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return downExtractDriver.ApplyDownOperationContext(context.Background(), message, downOperation)
+}
 
-	var messageJson interface{} = message
+func (downExtractDriver *DownExtractDriverOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	messageJson, err := jsonView(ctx, message)
+	if err != nil {
+		return nil, err
+	}
 	var command = message.Command
 	var resultJson interface{}
-	var err error
 	jmesPathOperation := (*downOperation).(ontology.DownExtractDriverMessage)
 	if element, ok := jmesPathOperation.Commands[command.Id]; ok {
-		resultJson, err = util.ExtractMessage(messageJson, element)
+		resultJson, err = util.ExtractMessageContext(ctx, messageJson, element)
 		if err != nil {
-			return nil, err
+			return nil, util.WithKey(err, command.Id)
 		}
 	}
 	if resultJson == nil {
 		value, ok := jmesPathOperation.Commands["default"]
 		if ok {
-			resultJson, err = util.ExtractMessage(messageJson, value)
+			resultJson, err = util.ExtractMessageContext(ctx, messageJson, value)
 			if err != nil {
-				return nil, err
+				return nil, util.WithKey(err, "default")
 			}
 		}
 	}
@@ -39,3 +53,27 @@ func (downExtractDriver *DownExtractDriverOperation) ApplyDownOperation(message
 	retMessage.Packet.Message = resultJson
 	return retMessage, nil
 }
+
+func (downExtractDriver *DownExtractDriverOperation) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	return nil
+}
+
+func (downExtractDriver *DownExtractDriverOperation) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	var expressions []string
+	for _, command := range downOperation.(ontology.DownExtractDriverMessage).Commands {
+		expressions = collectExpressions(command, expressions)
+	}
+	return expressions
+}
+
+func (downExtractDriver *DownExtractDriverOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	return nil
+}
+
+func (downExtractDriver *DownExtractDriverOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	var problems []*util.MappingError
+	for key, command := range downOperation.(ontology.DownExtractDriverMessage).Commands {
+		problems = append(problems, validateExpressions(key, collectExpressions(command, nil))...)
+	}
+	return problems
+}
@@ -134,3 +134,21 @@ func Test_should_extract_command_if_command_is_jmesexpression_downmessage(t *tes
 	assert.Equal(t, outputMessage, expectedOutputMessage)
 
 }
+
+func Test_should_copy_constant_command_members_downmessage(t *testing.T) {
+	// Given
+	inputDownMessage := buildInputDownMessage("downmessage_sample.json")
+	commands := map[string]interface{}{
+		"myDeviceCommand": map[string]interface{}{"input": map[string]interface{}{"value": 10.0, "enabled": true, "levels": []interface{}{1.0, 2.0}, "type": "{{ command.id }}"}},
+	}
+	var downOpr ontology.DownOperationInterface = ontology.DownExtractDriverMessage{Commands: commands}
+	var operations = OperationsDownSerDer{Operations: []ontology.DownOperationInterface{downOpr}}
+	//When
+	validationErr := operations.Validate()
+	outputMessage, err := extractMessageOperation.ApplyDownOperation(&inputDownMessage, &downOpr)
+	//Then
+	assert.Nil(t, validationErr)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"input": map[string]interface{}{"value": 10.0, "enabled": true, "levels": []interface{}{1.0, 2.0}, "type": "myDeviceCommand"}},
+		outputMessage.Packet.Message)
+}
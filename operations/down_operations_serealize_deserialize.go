@@ -2,7 +2,6 @@ package operations
 
 import (
 	"encoding/json"
-	"errors"
 	"ontology-mapping-go-lib/models/ontology"
 )
 
@@ -18,22 +17,14 @@ func (opr *OperationsDownSerDer) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	for _, raw := range opr.RawOperations {
+	for index, raw := range opr.RawOperations {
 		var operation ontology.DownOperation
 		err = json.Unmarshal(raw, &operation)
 		if err != nil {
 			return err
 		}
 		var i ontology.DownOperationInterface
-		switch operation.Op {
-		case "extractDriverMessage":
-			i = &ontology.DownExtractDriverMessage{}
-		case "updateCommand":
-			i = &ontology.DownUpdateCommand{}
-		default:
-			return errors.New("unknown operation type")
-		}
-		err = json.Unmarshal(raw, i)
+		i, err = decodeDownOperation(index, operation.Op, raw)
 		if err != nil {
 			return err
 		}
@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"context"
 	"ontology-mapping-go-lib/models/flow"
 )
 import "ontology-mapping-go-lib/models/ontology"
@@ -9,11 +10,23 @@ import "ontology-mapping-go-lib/util"
 type DownUpdateCommandOperation struct {
 }
 
+func init() {
+	MustRegisterDownOperation("updateCommand", func() ontology.DownOperationInterface { return &ontology.DownUpdateCommand{} }, func() OperationHandler { return &DownUpdateCommandOperation{} })
+}
+
 func (downUpdateCommand *DownUpdateCommandOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
 	return nil, nil
 }
 
+func (downUpdateCommand *DownUpdateCommandOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return nil, nil
+}
+
 func (downUpdateCommand *DownUpdateCommandOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return downUpdateCommand.ApplyDownOperationContext(context.Background(), message, downOperation)
+}
+
+func (downUpdateCommand *DownUpdateCommandOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
 	var retMessage = util.CopyDownMessage(message)
 	jmesPathOperation := (*downOperation).(ontology.DownUpdateCommand)
 	command := message.Command
@@ -23,9 +36,9 @@ func (downUpdateCommand *DownUpdateCommandOperation) ApplyDownOperation(message
 			retMessage.Command.Id = element.Id
 		}
 		if element.Input != nil {
-			retMessage.Command.Input, err = util.ExtractCommands(command, element.Input)
+			retMessage.Command.Input, err = util.ExtractCommandsContext(ctx, command, element.Input)
 			if err != nil {
-				return nil, err
+				return nil, util.WithKey(err, command.Id)
 			}
 		}
 		return retMessage, nil
@@ -35,11 +48,38 @@ func (downUpdateCommand *DownUpdateCommandOperation) ApplyDownOperation(message
 			retMessage.Command.Id = value.Id
 		}
 		if value.Input != nil {
-			retMessage.Command.Input, err = util.ExtractCommands(command, value.Input)
+			retMessage.Command.Input, err = util.ExtractCommandsContext(ctx, command, value.Input)
 			if err != nil {
-				return nil, err
+				return nil, util.WithKey(err, "default")
 			}
 		}
 	}
 	return retMessage, nil
 }
+
+func (downUpdateCommand *DownUpdateCommandOperation) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	return nil
+}
+
+func (downUpdateCommand *DownUpdateCommandOperation) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	var expressions []string
+	for _, command := range downOperation.(ontology.DownUpdateCommand).Commands {
+		expressions = collectExpressions(command.Input, expressions)
+	}
+	return expressions
+}
+
+func (downUpdateCommand *DownUpdateCommandOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	return nil
+}
+
+func (downUpdateCommand *DownUpdateCommandOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	var problems []*util.MappingError
+	for key, command := range downOperation.(ontology.DownUpdateCommand).Commands {
+		if command.Input == nil {
+			continue
+		}
+		problems = append(problems, validateCommand(key, command.Input)...)
+	}
+	return problems
+}
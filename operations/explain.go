@@ -0,0 +1,132 @@
+package operations
+
+import (
+	"context"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+)
+
+// UpOperationTrace describes what one operation did to an up message.
+type UpOperationTrace struct {
+	Index       int                         `json:"index"`
+	Op          string                      `json:"op"`
+	Before      *flow.UpMessage             `json:"before"`
+	After       *flow.UpMessage             `json:"after"`
+	Expressions []util.ExpressionEvaluation `json:"expressions,omitempty"`
+	DropReason  string                      `json:"dropReason,omitempty"`
+	Error       string                      `json:"error,omitempty"`
+}
+
+// DownOperationTrace describes what one operation did to a down message.
+type DownOperationTrace struct {
+	Index       int                         `json:"index"`
+	Op          string                      `json:"op"`
+	Before      *flow.DownMessage           `json:"before"`
+	After       *flow.DownMessage           `json:"after"`
+	Expressions []util.ExpressionEvaluation `json:"expressions,omitempty"`
+	DropReason  string                      `json:"dropReason,omitempty"`
+	Error       string                      `json:"error,omitempty"`
+}
+
+type UpExplanation struct {
+	Operations []UpOperationTrace `json:"operations"`
+	Message    *flow.UpMessage    `json:"message"`
+}
+
+type DownExplanation struct {
+	Operations []DownOperationTrace `json:"operations"`
+	Message    *flow.DownMessage    `json:"message"`
+}
+
+// ExplainUpOperations applies the operations like ApplyUpOperations and traces
+// every step. On error the explanation covers the operations up to the failing one.
+func (operationService *OperationService) ExplainUpOperations(message *flow.UpMessage, operations *OperationsUpSerDer) (*UpExplanation, error) {
+	return operationService.ExplainUpOperationsContext(context.Background(), message, operations)
+}
+
+func (operationService *OperationService) ExplainUpOperationsContext(ctx context.Context, message *flow.UpMessage, operations *OperationsUpSerDer) (*UpExplanation, error) {
+	var explanation = new(UpExplanation)
+	var retMessage = message
+	for index, operation := range operations.Operations {
+		if retMessage == nil {
+			break
+		}
+		var trace = UpOperationTrace{Index: index, Op: operation.ValidUpOperation(), Before: retMessage}
+		handler, err := operationService.Factory.BuildUp(operation)
+		if err != nil {
+			err = util.WithOperation(err, index, operation.ValidUpOperation())
+			trace.Error = err.Error()
+			explanation.Operations = append(explanation.Operations, trace)
+			return explanation, err
+		}
+		var recorder = new(util.ExpressionRecorder)
+		var operationCtx = withOperationScope(util.WithExpressionRecorder(ctx, recorder), operationService.Factory, nil)
+		retMessage, err = applyUpOperation(operationCtx, operationService.OperationTimeout, nil, index, handler, retMessage, &operation)
+		trace.After = retMessage
+		trace.Expressions = recorder.Evaluations()
+		if err != nil {
+			trace.Error = err.Error()
+			explanation.Operations = append(explanation.Operations, trace)
+			return explanation, err
+		}
+		if retMessage == nil {
+			trace.DropReason = explainUpDrop(handler, trace.Before, &operation)
+		}
+		explanation.Operations = append(explanation.Operations, trace)
+	}
+	explanation.Message = retMessage
+	return explanation, nil
+}
+
+func (operationService *OperationService) ExplainDownOperations(message *flow.DownMessage, operations *OperationsDownSerDer) (*DownExplanation, error) {
+	return operationService.ExplainDownOperationsContext(context.Background(), message, operations)
+}
+
+func (operationService *OperationService) ExplainDownOperationsContext(ctx context.Context, message *flow.DownMessage, operations *OperationsDownSerDer) (*DownExplanation, error) {
+	var explanation = new(DownExplanation)
+	var retMessage = message
+	for index, operation := range operations.Operations {
+		if retMessage == nil {
+			break
+		}
+		var trace = DownOperationTrace{Index: index, Op: operation.ValidDownOperation(), Before: retMessage}
+		handler, err := operationService.Factory.BuildDown(operation)
+		if err != nil {
+			err = util.WithOperation(err, index, operation.ValidDownOperation())
+			trace.Error = err.Error()
+			explanation.Operations = append(explanation.Operations, trace)
+			return explanation, err
+		}
+		var recorder = new(util.ExpressionRecorder)
+		var operationCtx = withOperationScope(util.WithExpressionRecorder(ctx, recorder), operationService.Factory, nil)
+		retMessage, err = applyDownOperation(operationCtx, operationService.OperationTimeout, nil, index, handler, retMessage, &operation)
+		trace.After = retMessage
+		trace.Expressions = recorder.Evaluations()
+		if err != nil {
+			trace.Error = err.Error()
+			explanation.Operations = append(explanation.Operations, trace)
+			return explanation, err
+		}
+		if retMessage == nil {
+			trace.DropReason = explainDownDrop(handler, trace.Before, &operation)
+		}
+		explanation.Operations = append(explanation.Operations, trace)
+	}
+	explanation.Message = retMessage
+	return explanation, nil
+}
+
+func explainUpDrop(handler OperationHandler, message *flow.UpMessage, operation *ontology.UpOperationInterface) string {
+	if explainer, ok := handler.(DropExplainer); ok {
+		return explainer.ExplainUpDrop(message, operation)
+	}
+	return "the operation returned no message"
+}
+
+func explainDownDrop(handler OperationHandler, message *flow.DownMessage, operation *ontology.DownOperationInterface) string {
+	if explainer, ok := handler.(DropExplainer); ok {
+		return explainer.ExplainDownDrop(message, operation)
+	}
+	return "the operation returned no message"
+}
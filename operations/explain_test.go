@@ -0,0 +1,91 @@
+package operations
+
+import (
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+)
+
+var explainService = OperationService{Factory: OperationFactory{}}
+
+func Test_should_explain_each_up_operation_with_evaluated_expressions(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceUplink: true}
+	operations.Operations = append([]ontology.UpOperationInterface{filterOpr}, operations.Operations...)
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	explanation, err := explainService.ExplainUpOperations(&inputUpMessage, operations)
+	// Then
+	assert.Nil(t, err)
+	expectedUpMessage, _ := explainService.ApplyUpOperations(&inputUpMessage, operations)
+	assert.Equal(t, expectedUpMessage, explanation.Message)
+	assert.Equal(t, 2, len(explanation.Operations))
+	assert.Equal(t, "filter", explanation.Operations[0].Op)
+	assert.Equal(t, &inputUpMessage, explanation.Operations[0].Before)
+	assert.Equal(t, &inputUpMessage, explanation.Operations[0].After)
+	assert.Empty(t, explanation.Operations[0].Expressions)
+	assert.Equal(t, "extractPoints", explanation.Operations[1].Op)
+	assert.Equal(t, explanation.Message, explanation.Operations[1].After)
+	assert.ElementsMatch(t, []util.ExpressionEvaluation{
+		{Expression: "{{packet.message.temperature}}", Result: 22.6},
+		{Expression: "{{time}}", Result: "2020-01-01T10:00:00Z"},
+	}, explanation.Operations[1].Expressions)
+}
+
+func Test_should_explain_why_filter_dropped_the_message(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceLocation: true}
+	operations.Operations = append([]ontology.UpOperationInterface{filterOpr}, operations.Operations...)
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	explanation, err := explainService.ExplainUpOperations(&inputUpMessage, operations)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, explanation.Message)
+	assert.Equal(t, 1, len(explanation.Operations))
+	assert.Nil(t, explanation.Operations[0].After)
+	assert.Equal(t, "message type 'deviceUplink' is not kept, 'keepDeviceUplink' is false", explanation.Operations[0].DropReason)
+}
+
+func Test_should_explain_filter_drop_for_notification_sub_type(t *testing.T) {
+	// Given
+	inputUpMessage := buildInputUpMessageFilter("deviceNotification")
+	inputUpMessage.SubType = "battery"
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceNotification: true, KeepDeviceNotificationSubTypes: []string{"alarm"}}
+	// When
+	explanation, _ := explainService.ExplainUpOperations(&inputUpMessage, &OperationsUpSerDer{Operations: []ontology.UpOperationInterface{filterOpr}})
+	// Then
+	assert.Equal(t, "notification subType 'battery' is not in 'keepDeviceNotificationSubTypes' [alarm]", explanation.Operations[0].DropReason)
+}
+
+func Test_should_explain_up_operations_up_to_the_failing_one(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.[temperature}}")
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	explanation, err := explainService.ExplainUpOperations(&inputUpMessage, operations)
+	// Then
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, len(explanation.Operations))
+	assert.Equal(t, err.Error(), explanation.Operations[0].Error)
+	assert.Equal(t, "{{packet.message.[temperature}}", explanation.Operations[0].Expressions[0].Expression)
+	assert.NotEmpty(t, explanation.Operations[0].Expressions[0].Error)
+}
+
+func Test_should_explain_down_operations(t *testing.T) {
+	// Given
+	var downOpr ontology.DownOperationInterface = ontology.DownUpdateCommand{Commands: map[string]ontology.UpdateCommand{
+		"myDeviceCommand": {Id: "newCommandId", Input: "{{input.prop1}}"},
+	}}
+	inputDownMessage := buildInputDownMessage("update_command_id.json")
+	// When
+	explanation, err := explainService.ExplainDownOperations(&inputDownMessage, &OperationsDownSerDer{Operations: []ontology.DownOperationInterface{downOpr}})
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "newCommandId", explanation.Message.Command.Id)
+	assert.Equal(t, "myDeviceCommand", explanation.Operations[0].Before.Command.Id)
+	assert.Equal(t, []util.ExpressionEvaluation{{Expression: "{{input.prop1}}", Result: 10.0}}, explanation.Operations[0].Expressions)
+}
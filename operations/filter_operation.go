@@ -1,6 +1,8 @@
 package operations
 
 import (
+	"context"
+	"fmt"
 	"ontology-mapping-go-lib/models/flow"
 )
 import "ontology-mapping-go-lib/models/ontology"
@@ -9,27 +11,202 @@ import "ontology-mapping-go-lib/util"
 type FilterOperation struct {
 }
 
+func init() {
+	MustRegisterUpOperation("filter", func() ontology.UpOperationInterface { return &ontology.UpFilterOperation{} }, func() OperationHandler { return &FilterOperation{} })
+	MustRegisterDownOperation("filter", func() ontology.DownOperationInterface { return &ontology.DownFilterOperation{} }, func() OperationHandler { return &FilterOperation{} })
+}
+
 func (filterOperation *FilterOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return filterOperation.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
 
+func (filterOperation *FilterOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
 	var upFilterOperation = (*upOperation).(ontology.UpFilterOperation)
-	if isFilterPresent(upFilterOperation.KeepDeviceDownlinkSent, string(message.Type_), "deviceDownlinkSent") {
+	if hasTypeFilter(upFilterOperation) || len(upFilterOperation.Predicate) == 0 {
+		if !isTypeKept(upFilterOperation, message) {
+			return nil, nil
+		}
+	}
+	if len(upFilterOperation.Predicate) == 0 {
 		return message, nil
 	}
+	keep, err := isPredicateKept(ctx, upFilterOperation.Predicate, upFilterOperation.Mode, message)
+	if err != nil || !keep {
+		return nil, err
+	}
+	return message, nil
+}
+
+func isTypeKept(upFilterOperation ontology.UpFilterOperation, message *flow.UpMessage) bool {
+	if isFilterPresent(upFilterOperation.KeepDeviceDownlinkSent, string(message.Type_), "deviceDownlinkSent") {
+		return true
+	}
 	if isFilterPresent(upFilterOperation.KeepDeviceUplink, string(message.Type_), "deviceUplink") {
-		return message, nil
+		return true
 	}
 	if isFilterPresent(upFilterOperation.KeepDeviceLocation, string(message.Type_), "deviceLocation") {
-		return message, nil
+		return true
 	}
 	if isFilterPresent(upFilterOperation.KeepDeviceNotification, string(message.Type_), "deviceNotification") &&
 		isSubtypePresent(upFilterOperation.KeepDeviceNotificationSubTypes, message.SubType) {
-		return message, nil
+		return true
+	}
+	return false
+}
+
+func hasTypeFilter(upFilterOperation ontology.UpFilterOperation) bool {
+	return upFilterOperation.KeepDeviceUplink || upFilterOperation.KeepDeviceDownlinkSent ||
+		upFilterOperation.KeepDeviceLocation || upFilterOperation.KeepDeviceNotification
+}
+
+// isPredicateKept tells whether mode keeps a message on which predicate holds or
+// does not.
+func isPredicateKept(ctx context.Context, predicate string, mode ontology.FilterMode, message interface{}) (bool, error) {
+	if err := validateFilterMode(mode); err != nil {
+		return false, err
+	}
+	holds, err := evaluatePredicate(ctx, predicate, message)
+	if err != nil {
+		return false, err
+	}
+	return holds != (mode == ontology.DENY_FilterMode), nil
+}
+
+func validateFilterMode(mode ontology.FilterMode) *util.MappingError {
+	switch mode {
+	case "", ontology.ALLOW_FilterMode, ontology.DENY_FilterMode:
+		return nil
+	default:
+		return util.NewMappingError(util.INVALID_RULE_ErrorCode, fmt.Sprintf("unknown mode '%s', expected 'allow' or 'deny'", mode))
 	}
-	return nil, nil
 }
 
 func (filterOperation *FilterOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
-	return nil, nil
+	return filterOperation.ApplyDownOperationContext(context.Background(), message, downOperation)
+}
+
+// ApplyDownOperationContext returns the message unchanged when its command passes
+// the filter, and a COMMAND_REJECTED_ErrorCode error naming the failed check
+// otherwise.
+func (filterOperation *FilterOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	var downFilterOperation = (*downOperation).(ontology.DownFilterOperation)
+	var commandId string
+	if message.Command != nil {
+		commandId = message.Command.Id
+	}
+	var reject = func(format string, arguments ...interface{}) (*flow.DownMessage, error) {
+		return nil, &util.MappingError{Code: util.COMMAND_REJECTED_ErrorCode, Key: commandId, Message: fmt.Sprintf(format, arguments...)}
+	}
+	if len(downFilterOperation.AllowCommands) > 0 && !util.Contains(downFilterOperation.AllowCommands, commandId) {
+		return reject("command '%s' is not in 'allowCommands'", commandId)
+	}
+	if util.Contains(downFilterOperation.DenyCommands, commandId) {
+		return reject("command '%s' is in 'denyCommands'", commandId)
+	}
+	if len(downFilterOperation.OriginTypes) > 0 {
+		var originType string
+		if message.Origin != nil {
+			originType = string(message.Origin.Type_)
+		}
+		if !util.Contains(downFilterOperation.OriginTypes, originType) {
+			return reject("origin type '%s' is not in 'originTypes'", originType)
+		}
+	}
+	var predicates = []struct {
+		field     string
+		predicate string
+		value     interface{}
+	}{
+		{"thing", downFilterOperation.Thing, message.Thing},
+		{"subAccount", downFilterOperation.SubAccount, message.SubAccount},
+	}
+	for _, predicate := range predicates {
+		if len(predicate.predicate) == 0 {
+			continue
+		}
+		holds, err := evaluatePredicate(ctx, predicate.predicate, predicate.value)
+		if err != nil {
+			return nil, err
+		}
+		if !holds {
+			return reject("'%s' predicate '%s' does not hold", predicate.field, predicate.predicate)
+		}
+	}
+	return message, nil
+}
+
+func (filterOperation *FilterOperation) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	return []string{upOperation.(ontology.UpFilterOperation).Predicate}
+}
+
+func (filterOperation *FilterOperation) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	var downFilterOperation = downOperation.(ontology.DownFilterOperation)
+	return []string{downFilterOperation.Thing, downFilterOperation.SubAccount}
+}
+
+func (filterOperation *FilterOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var upFilterOperation = upOperation.(ontology.UpFilterOperation)
+	if len(upFilterOperation.Predicate) == 0 {
+		if len(upFilterOperation.Mode) > 0 {
+			return []*util.MappingError{util.NewMappingError(util.INVALID_RULE_ErrorCode, "'mode' needs a 'predicate'")}
+		}
+		return nil
+	}
+	var problems = validatePredicate("predicate", upFilterOperation.Predicate)
+	if err := validateFilterMode(upFilterOperation.Mode); err != nil {
+		problems = append(problems, err)
+	}
+	return problems
+}
+
+func (filterOperation *FilterOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	var downFilterOperation = downOperation.(ontology.DownFilterOperation)
+	var problems []*util.MappingError
+	if len(downFilterOperation.Thing) > 0 {
+		problems = append(problems, validatePredicate("thing", downFilterOperation.Thing)...)
+	}
+	if len(downFilterOperation.SubAccount) > 0 {
+		problems = append(problems, validatePredicate("subAccount", downFilterOperation.SubAccount)...)
+	}
+	for _, commandId := range downFilterOperation.DenyCommands {
+		if util.Contains(downFilterOperation.AllowCommands, commandId) {
+			problems = append(problems, &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Key: commandId,
+				Message: "command is both in 'allowCommands' and 'denyCommands'"})
+		}
+	}
+	return problems
+}
+
+func (filterOperation *FilterOperation) ExplainUpDrop(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) string {
+	var upFilterOperation = (*upOperation).(ontology.UpFilterOperation)
+	if len(upFilterOperation.Predicate) > 0 && (!hasTypeFilter(upFilterOperation) || isTypeKept(upFilterOperation, message)) {
+		if upFilterOperation.Mode == ontology.DENY_FilterMode {
+			return fmt.Sprintf("predicate '%s' holds in deny mode", upFilterOperation.Predicate)
+		}
+		return fmt.Sprintf("predicate '%s' does not hold", upFilterOperation.Predicate)
+	}
+	var keep bool
+	var flag string
+	switch message.Type_ {
+	case flow.DEVICE_UPLINK_UpMessageType:
+		keep, flag = upFilterOperation.KeepDeviceUplink, "keepDeviceUplink"
+	case flow.DEVICE_DOWNLINK_SENT_UpMessageType:
+		keep, flag = upFilterOperation.KeepDeviceDownlinkSent, "keepDeviceDownlinkSent"
+	case flow.DEVICE_LOCATION_UpMessageType:
+		keep, flag = upFilterOperation.KeepDeviceLocation, "keepDeviceLocation"
+	case flow.DEVICE_NOTIFICATION_UpMessageType:
+		keep, flag = upFilterOperation.KeepDeviceNotification, "keepDeviceNotification"
+	default:
+		return fmt.Sprintf("message type '%s' is never kept by the filter", message.Type_)
+	}
+	if !keep {
+		return fmt.Sprintf("message type '%s' is not kept, '%s' is false", message.Type_, flag)
+	}
+	return fmt.Sprintf("notification subType '%s' is not in 'keepDeviceNotificationSubTypes' %v", message.SubType, upFilterOperation.KeepDeviceNotificationSubTypes)
+}
+
+func (filterOperation *FilterOperation) ExplainDownDrop(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) string {
+	return "the filter operation rejects commands with an error rather than dropping them"
 }
 
 func isFilterPresent(filterVal bool, messageType string, typeString string) bool {
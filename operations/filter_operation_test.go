@@ -1,6 +1,9 @@
 package operations
 
 import (
+	"encoding/json"
+	"errors"
+	"ontology-mapping-go-lib/util"
 	"testing"
 	"time"
 )
@@ -331,3 +334,130 @@ func Test_should_exclude_message_for_keep_device_notification_true_sub_type_not_
 	// Then
 	assert.Nil(t, outputMessage)
 }
+
+func Test_should_keep_message_matching_predicate_in_allow_mode(t *testing.T) {
+	// Given
+	inputUpMessage := buildInputUpMessageFilter("deviceNotification")
+	inputUpMessage.Thing.Model = &flow.ModuleSpec{ProducerId: "abeeway", ModuleId: "industrial-tracker", Version: "1"}
+	otherUpMessage := buildInputUpMessageFilter("deviceUplink")
+	otherUpMessage.Thing.Model = &flow.ModuleSpec{ProducerId: "adeunis", ModuleId: "field-test-device", Version: "1"}
+	var upFilterOperation ontology.UpOperationInterface = ontology.UpFilterOperation{Predicate: "{{thing.model.moduleId == 'industrial-tracker'}}"}
+	// When
+	outputMessage, err := filterOperation.ApplyUpOperation(&inputUpMessage, &upFilterOperation)
+	otherOutputMessage, otherErr := filterOperation.ApplyUpOperation(&otherUpMessage, &upFilterOperation)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, otherErr)
+	assert.Equal(t, &inputUpMessage, outputMessage)
+	assert.Nil(t, otherOutputMessage)
+}
+
+func Test_should_drop_message_matching_predicate_in_deny_mode(t *testing.T) {
+	// Given
+	inputUpMessage := buildInputUpMessageFilter("deviceUplink")
+	inputUpMessage.Thing.Tags = []string{"test-bench"}
+	otherUpMessage := buildInputUpMessageFilter("deviceUplink")
+	var upFilterOperation ontology.UpOperationInterface = ontology.UpFilterOperation{Predicate: "{{contains(thing.tags || `[]`, 'test-bench')}}", Mode: ontology.DENY_FilterMode}
+	// When
+	outputMessage, err := filterOperation.ApplyUpOperation(&inputUpMessage, &upFilterOperation)
+	otherOutputMessage, otherErr := filterOperation.ApplyUpOperation(&otherUpMessage, &upFilterOperation)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, otherErr)
+	assert.Nil(t, outputMessage)
+	assert.Equal(t, &otherUpMessage, otherOutputMessage)
+}
+
+func Test_should_apply_predicate_to_messages_kept_by_type(t *testing.T) {
+	// Given
+	uplinkUpMessage := buildInputUpMessageFilter("deviceUplink")
+	locationUpMessage := buildInputUpMessageFilter("deviceLocation")
+	otherRealmUpMessage := buildInputUpMessageFilter("deviceUplink")
+	otherRealmUpMessage.SubAccount.RealmId = "realm2"
+	var upFilterOperation ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceUplink: true, Predicate: "{{subAccount.realmId == 'realm1'}}"}
+	// When
+	uplinkOutputMessage, _ := filterOperation.ApplyUpOperation(&uplinkUpMessage, &upFilterOperation)
+	locationOutputMessage, _ := filterOperation.ApplyUpOperation(&locationUpMessage, &upFilterOperation)
+	otherRealmOutputMessage, _ := filterOperation.ApplyUpOperation(&otherRealmUpMessage, &upFilterOperation)
+	// Then
+	assert.Equal(t, &uplinkUpMessage, uplinkOutputMessage)
+	assert.Nil(t, locationOutputMessage)
+	assert.Nil(t, otherRealmOutputMessage)
+}
+
+func Test_should_explain_predicate_filter_drop(t *testing.T) {
+	// Given
+	inputUpMessage := buildInputUpMessageFilter("deviceUplink")
+	var upFilterOperation ontology.UpOperationInterface = ontology.UpFilterOperation{Predicate: "{{subType}}", Mode: ontology.ALLOW_FilterMode}
+	// When
+	reason := filterOperation.ExplainUpDrop(&inputUpMessage, &upFilterOperation)
+	// Then
+	assert.Equal(t, "predicate '{{subType}}' does not hold", reason)
+}
+
+func Test_should_report_invalid_filter_mode(t *testing.T) {
+	// Given
+	var upFilterOperation ontology.UpOperationInterface = ontology.UpFilterOperation{Predicate: "subAccount.realmId", Mode: "block"}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{upFilterOperation}}
+	// When
+	err := operations.Validate()
+	// Then
+	assert.NotNil(t, err)
+	assert.Equal(t, "2 problem(s) found in mapping: operation 0 'filter': unknown mode 'block', expected 'allow' or 'deny'; "+
+		"operation 0 'filter', expression 'subAccount.realmId': 'predicate' must be a '{{ }}' template", err.Error())
+}
+
+func Test_should_pass_command_allowed_by_down_filter(t *testing.T) {
+	// Given
+	inputDownMessage := buildInputDownMessage("update_command_id.json")
+	inputDownMessage.Thing.Model = &flow.ModuleSpec{ProducerId: "abeeway", ModuleId: "industrial-tracker", Version: "1"}
+	var downFilterOperation ontology.DownOperationInterface = ontology.DownFilterOperation{
+		AllowCommands: []string{"myDeviceCommand", "otherCommand"},
+		OriginTypes:   []string{"binder"},
+		Thing:         "{{model.producerId == 'abeeway'}}",
+		SubAccount:    "{{realmId == 'realm1'}}",
+	}
+	// When
+	outputMessage, err := filterOperation.ApplyDownOperation(&inputDownMessage, &downFilterOperation)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, &inputDownMessage, outputMessage)
+}
+
+func Test_should_reject_command_failing_down_filter(t *testing.T) {
+	// Given
+	inputDownMessage := buildInputDownMessage("update_command_id.json")
+	var downFilterOperations = map[string]ontology.DownFilterOperation{
+		"command 'myDeviceCommand' is not in 'allowCommands'":            {AllowCommands: []string{"otherCommand"}},
+		"command 'myDeviceCommand' is in 'denyCommands'":                 {DenyCommands: []string{"myDeviceCommand"}},
+		"origin type 'binder' is not in 'originTypes'":                   {OriginTypes: []string{"connector"}},
+		"'thing' predicate '{{model.producerId}}' does not hold":         {Thing: "{{model.producerId}}"},
+		"'subAccount' predicate '{{realmId == 'realm2'}}' does not hold": {SubAccount: "{{realmId == 'realm2'}}"},
+	}
+	for expectedMessage, downFilterOperation := range downFilterOperations {
+		var downOpr ontology.DownOperationInterface = downFilterOperation
+		// When
+		outputMessage, err := filterOperation.ApplyDownOperation(&inputDownMessage, &downOpr)
+		// Then
+		assert.Nil(t, outputMessage)
+		assert.True(t, errors.Is(err, util.ErrCommandRejected))
+		assert.Equal(t, expectedMessage, err.Error())
+		assert.Equal(t, "myDeviceCommand", err.(*util.MappingError).Key)
+	}
+}
+
+func Test_should_decode_and_validate_down_filter(t *testing.T) {
+	// Given
+	var operations OperationsDownSerDer
+	err := json.Unmarshal([]byte(`{"operations":[{"op":"filter","allowCommands":["reboot"],"denyCommands":["reboot"],"thing":"model.producerId"}]}`), &operations)
+	assert.Nil(t, err)
+	// When
+	err = operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, len(validationErr.Problems))
+	assert.Equal(t, "operation 0 'filter', expression 'model.producerId': 'thing' must be a '{{ }}' template", validationErr.Problems[0].Error())
+	assert.Equal(t, util.INVALID_RULE_ErrorCode, validationErr.Problems[1].Code)
+	assert.Equal(t, "reboot", validationErr.Problems[1].Key)
+}
@@ -9,6 +9,10 @@ import "ontology-mapping-go-lib/util"
 type FilterPointsOperation struct {
 }
 
+func init() {
+	MustRegisterUpOperation("filterPoints", func() ontology.UpOperationInterface { return &ontology.UpFilterPointsOperation{} }, func() OperationHandler { return &FilterPointsOperation{} })
+}
+
 func (filterPointsOperation *FilterPointsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
 	var retMessage = util.CopyUpMessage(message)
 	var points []string
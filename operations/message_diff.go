@@ -0,0 +1,217 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"reflect"
+	"sort"
+)
+
+type DiffKind string
+
+const (
+	ADDED_DiffKind   DiffKind = "added"
+	REMOVED_DiffKind DiffKind = "removed"
+	CHANGED_DiffKind DiffKind = "changed"
+)
+
+// FieldChange is a field whose value differs between two messages, Field is a
+// dotted path such as "records[0].value" or "input.prop1".
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// PointDiff describes a point added, removed or changed, Changes is only set
+// for changed points.
+type PointDiff struct {
+	Key     string        `json:"key"`
+	Kind    DiffKind      `json:"kind"`
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// UpMessageDiff groups the changes by part of the message, Message holds the
+// changes to its subType, thing tags and content.
+type UpMessageDiff struct {
+	Dropped bool          `json:"dropped,omitempty"`
+	Message []FieldChange `json:"message,omitempty"`
+	Points  []PointDiff   `json:"points,omitempty"`
+	Packet  []FieldChange `json:"packet,omitempty"`
+}
+
+// DownMessageDiff is the down equivalent of UpMessageDiff, Message holds the
+// changes to its thing tags and content.
+type DownMessageDiff struct {
+	Dropped bool          `json:"dropped,omitempty"`
+	Message []FieldChange `json:"message,omitempty"`
+	Command []FieldChange `json:"command,omitempty"`
+	Packet  []FieldChange `json:"packet,omitempty"`
+}
+
+func (messageDiff UpMessageDiff) IsEmpty() bool {
+	return !messageDiff.Dropped && len(messageDiff.Message) == 0 && len(messageDiff.Points) == 0 && len(messageDiff.Packet) == 0
+}
+
+func (messageDiff DownMessageDiff) IsEmpty() bool {
+	return !messageDiff.Dropped && len(messageDiff.Message) == 0 && len(messageDiff.Command) == 0 && len(messageDiff.Packet) == 0
+}
+
+// DiffUpMessages reports what changed in the subType, thing tags, content, points
+// and packet from before to after, a nil after means the message was dropped.
+// The other fields, such as the origin or the subscriber, are not compared.
+func DiffUpMessages(before *flow.UpMessage, after *flow.UpMessage) UpMessageDiff {
+	var messageDiff UpMessageDiff
+	if before == nil || after == nil {
+		messageDiff.Dropped = before != nil
+		return messageDiff
+	}
+	messageDiff.Message = diffValues("subType", before.SubType, after.SubType, nil)
+	messageDiff.Message = diffValues("thing.tags", thingTags(before.Thing), thingTags(after.Thing), messageDiff.Message)
+	messageDiff.Message = diffValues("content", before.Content, after.Content, messageDiff.Message)
+	messageDiff.Points = diffPoints(before.Points, after.Points)
+	messageDiff.Packet = diffPackets(before.Packet, after.Packet)
+	return messageDiff
+}
+
+// DiffDownMessages reports what changed in the thing tags, content, command and
+// packet from before to after, a nil after means the message was dropped. The
+// other fields, such as the origin or the subscriber, are not compared.
+func DiffDownMessages(before *flow.DownMessage, after *flow.DownMessage) DownMessageDiff {
+	var messageDiff DownMessageDiff
+	if before == nil || after == nil {
+		messageDiff.Dropped = before != nil
+		return messageDiff
+	}
+	messageDiff.Message = diffValues("thing.tags", thingTags(before.Thing), thingTags(after.Thing), nil)
+	messageDiff.Message = diffValues("content", before.Content, after.Content, messageDiff.Message)
+	messageDiff.Command = diffCommands(before.Command, after.Command)
+	messageDiff.Packet = diffPackets(before.Packet, after.Packet)
+	return messageDiff
+}
+
+func diffPoints(before map[string]flow.Point, after map[string]flow.Point) []PointDiff {
+	var pointDiffs []PointDiff
+	for _, key := range unionKeys(before, after) {
+		beforePoint, inBefore := before[key]
+		afterPoint, inAfter := after[key]
+		switch {
+		case !inBefore:
+			pointDiffs = append(pointDiffs, PointDiff{Key: key, Kind: ADDED_DiffKind})
+		case !inAfter:
+			pointDiffs = append(pointDiffs, PointDiff{Key: key, Kind: REMOVED_DiffKind})
+		default:
+			if changes := diffPoint(beforePoint, afterPoint); len(changes) > 0 {
+				pointDiffs = append(pointDiffs, PointDiff{Key: key, Kind: CHANGED_DiffKind, Changes: changes})
+			}
+		}
+	}
+	return pointDiffs
+}
+
+func diffPoint(before flow.Point, after flow.Point) []FieldChange {
+	var changes []FieldChange
+	changes = diffValues("ontologyId", before.OntologyId, after.OntologyId, changes)
+	changes = diffValues("unitId", before.UnitId, after.UnitId, changes)
+	changes = diffValues("type", string(before.Type_), string(after.Type_), changes)
+	var length = len(before.Records)
+	if len(after.Records) > length {
+		length = len(after.Records)
+	}
+	for index := 0; index < length; index++ {
+		var field = fmt.Sprintf("records[%d]", index)
+		switch {
+		case index >= len(before.Records):
+			changes = append(changes, FieldChange{Field: field, After: after.Records[index]})
+		case index >= len(after.Records):
+			changes = append(changes, FieldChange{Field: field, Before: before.Records[index]})
+		default:
+			var beforeRecord, afterRecord = before.Records[index], after.Records[index]
+			changes = diffValues(field+".value", beforeRecord.Value, afterRecord.Value, changes)
+			if !beforeRecord.EventTime.Equal(afterRecord.EventTime) {
+				changes = append(changes, FieldChange{Field: field + ".eventTime", Before: beforeRecord.EventTime, After: afterRecord.EventTime})
+			}
+			if !reflect.DeepEqual(beforeRecord.Coordinates, afterRecord.Coordinates) {
+				changes = append(changes, FieldChange{Field: field + ".coordinates", Before: beforeRecord.Coordinates, After: afterRecord.Coordinates})
+			}
+		}
+	}
+	return changes
+}
+
+func thingTags(thing *flow.Thing) []string {
+	if thing == nil {
+		return nil
+	}
+	return thing.Tags
+}
+
+func diffCommands(before *flow.Command, after *flow.Command) []FieldChange {
+	var beforeCommand, afterCommand flow.Command
+	if before != nil {
+		beforeCommand = *before
+	}
+	if after != nil {
+		afterCommand = *after
+	}
+	var changes = diffValues("id", beforeCommand.Id, afterCommand.Id, nil)
+	return diffValues("input", beforeCommand.Input, afterCommand.Input, changes)
+}
+
+func diffPackets(before *flow.MessagePacket, after *flow.MessagePacket) []FieldChange {
+	var beforePacket, afterPacket flow.MessagePacket
+	if before != nil {
+		beforePacket = *before
+	}
+	if after != nil {
+		afterPacket = *after
+	}
+	var changes = diffValues("type", beforePacket.Type_, afterPacket.Type_, nil)
+	changes = diffValues("raw", beforePacket.Raw, afterPacket.Raw, changes)
+	return diffValues("message", beforePacket.Message, afterPacket.Message, changes)
+}
+
+// diffValues walks nested objects so that a change deep inside a packet message or
+// a command input is reported on its own field rather than on the whole object.
+func diffValues(field string, before interface{}, after interface{}, changes []FieldChange) []FieldChange {
+	beforeFields, beforeIsObject := before.(map[string]interface{})
+	afterFields, afterIsObject := after.(map[string]interface{})
+	if beforeIsObject && afterIsObject {
+		for _, key := range unionKeys(beforeFields, afterFields) {
+			changes = diffValues(field+"."+key, beforeFields[key], afterFields[key], changes)
+		}
+		return changes
+	}
+	if !sameValue(before, after) {
+		changes = append(changes, FieldChange{Field: field, Before: before, After: after})
+	}
+	return changes
+}
+
+// sameValue compares the JSON forms, a mapping often turns an int into a float64
+// without changing what is sent on the wire.
+func sameValue(before interface{}, after interface{}) bool {
+	if reflect.DeepEqual(before, after) {
+		return true
+	}
+	beforeJson, beforeErr := json.Marshal(before)
+	afterJson, afterErr := json.Marshal(after)
+	return beforeErr == nil && afterErr == nil && string(beforeJson) == string(afterJson)
+}
+
+// unionKeys returns the sorted keys of two maps with string keys.
+func unionKeys(before interface{}, after interface{}) []string {
+	var keySet = make(map[string]bool)
+	for _, fields := range []interface{}{before, after} {
+		for _, key := range reflect.ValueOf(fields).MapKeys() {
+			keySet[key.String()] = true
+		}
+	}
+	var keys []string
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package operations
+
+import (
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"testing"
+	"time"
+)
+
+func Test_should_diff_points_and_packet_of_up_messages(t *testing.T) {
+	// Given
+	var eventTime = time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	before := flow.UpMessage{
+		Points: map[string]flow.Point{
+			"temperature": {UnitId: "Cel", Records: []flow.Record{{Value: 22, EventTime: eventTime}}},
+			"humidity":    {UnitId: "%RH", Records: []flow.Record{{Value: 40.0, EventTime: eventTime}}},
+		},
+		Packet: &flow.MessagePacket{Type_: "lora", Message: map[string]interface{}{"temperature": 22.0, "battery": 90.0}},
+	}
+	after := flow.UpMessage{
+		Points: map[string]flow.Point{
+			"temperature": {UnitId: "Far", Records: []flow.Record{{Value: 22.0, EventTime: eventTime}, {Value: 23.0, EventTime: eventTime}}},
+			"location":    {Records: []flow.Record{{Coordinates: []float64{7.0, 43.0}, EventTime: eventTime}}},
+		},
+		Packet: &flow.MessagePacket{Type_: "lora", Message: map[string]interface{}{"temperature": 22.0, "battery": 80.0}},
+	}
+	// When
+	messageDiff := DiffUpMessages(&before, &after)
+	// Then
+	assert.False(t, messageDiff.IsEmpty())
+	assert.Equal(t, []PointDiff{
+		{Key: "humidity", Kind: REMOVED_DiffKind},
+		{Key: "location", Kind: ADDED_DiffKind},
+		{Key: "temperature", Kind: CHANGED_DiffKind, Changes: []FieldChange{
+			{Field: "unitId", Before: "Cel", After: "Far"},
+			{Field: "records[1]", After: flow.Record{Value: 23.0, EventTime: eventTime}},
+		}},
+	}, messageDiff.Points)
+	assert.Equal(t, []FieldChange{{Field: "message.battery", Before: 90.0, After: 80.0}}, messageDiff.Packet)
+}
+
+func Test_should_diff_sub_type_tags_and_content_of_up_messages(t *testing.T) {
+	// Given
+	before := flow.UpMessage{
+		Thing:   &flow.Thing{Key: "lora:0123456789abcdef"},
+		Content: map[string]interface{}{"level": 1.0},
+	}
+	after := flow.UpMessage{
+		SubType: "zoneEntered",
+		Thing:   &flow.Thing{Key: "lora:0123456789abcdef", Tags: []string{"inside"}},
+		Content: map[string]interface{}{"level": 2.0},
+	}
+	// When
+	messageDiff := DiffUpMessages(&before, &after)
+	// Then
+	assert.False(t, messageDiff.IsEmpty())
+	assert.Equal(t, []FieldChange{
+		{Field: "subType", Before: "", After: "zoneEntered"},
+		{Field: "thing.tags", Before: []string(nil), After: []string{"inside"}},
+		{Field: "content.level", Before: 1.0, After: 2.0},
+	}, messageDiff.Message)
+}
+
+func Test_should_diff_command_of_down_messages(t *testing.T) {
+	// Given
+	before := flow.DownMessage{Command: &flow.Command{Id: "myDeviceCommand", Input: map[string]interface{}{"prop1": 10.0}}}
+	after := flow.DownMessage{Command: &flow.Command{Id: "newCommandId", Input: map[string]interface{}{"prop1": 10.0, "prop2": "on"}}}
+	// When
+	messageDiff := DiffDownMessages(&before, &after)
+	// Then
+	assert.Equal(t, []FieldChange{
+		{Field: "id", Before: "myDeviceCommand", After: "newCommandId"},
+		{Field: "input.prop2", After: "on"},
+	}, messageDiff.Command)
+	assert.Empty(t, messageDiff.Packet)
+}
+
+func Test_should_report_dropped_message_in_diff(t *testing.T) {
+	// Given
+	before := flow.UpMessage{}
+	// When
+	messageDiff := DiffUpMessages(&before, nil)
+	// Then
+	assert.True(t, messageDiff.Dropped)
+	assert.True(t, DiffUpMessages(&before, &before).IsEmpty())
+}
@@ -0,0 +1,42 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"ontology-mapping-go-lib/models/flow"
+)
+
+// messageView holds the JSON form of the message last searched within an apply.
+// Operations return a new message when they change it, so the view of a message
+// is built once however many predicates and templates search it.
+type messageView struct {
+	message interface{}
+	view    interface{}
+}
+
+// jsonView returns value in its JSON form, the form predicates and templates
+// search so that they see "type" or "time" as they are on the wire rather than
+// as Go fields. The view is shared, it must not be written into.
+func jsonView(ctx context.Context, value interface{}) (interface{}, error) {
+	var cache = operationScopeFrom(ctx).view
+	var cacheable bool
+	switch value.(type) {
+	case *flow.UpMessage, *flow.DownMessage:
+		cacheable = cache != nil
+	}
+	if cacheable && cache.message == value {
+		return cache.view, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var view interface{}
+	if err = json.Unmarshal(encoded, &view); err != nil {
+		return nil, err
+	}
+	if cacheable {
+		cache.message, cache.view = value, view
+	}
+	return view, nil
+}
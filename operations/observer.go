@@ -0,0 +1,154 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"reflect"
+	"time"
+)
+
+type Direction string
+
+const (
+	UP_Direction   Direction = "up"
+	DOWN_Direction Direction = "down"
+)
+
+// OperationEvent identifies the operation an Observer callback is about. Mapping
+// is the name given to the context with WithMappingName, empty otherwise. Index
+// is the position of the operation in its mapping, or in its branch for the
+// operations nested in a when operation.
+type OperationEvent struct {
+	Mapping   string
+	Direction Direction
+	Index     int
+	Op        string
+}
+
+// Observer is notified by OperationService and the compiled pipelines of every
+// operation they apply. Batches apply messages concurrently, so an Observer must
+// be safe for concurrent use.
+type Observer interface {
+	OperationStarted(event OperationEvent)
+	// OperationEnded is called after every started operation, failed or not.
+	OperationEnded(event OperationEvent, duration time.Duration)
+	// MessageDropped and OperationFailed are called once per message, for the
+	// operation that dropped it or failed, not for the when operations nesting it.
+	// ExplainUpOperations and ExplainDownOperations tell why a message was dropped.
+	MessageDropped(event OperationEvent)
+	// PointsProduced gives the number of records of each point that an up
+	// operation added or changed.
+	PointsProduced(event OperationEvent, recordsPerPoint map[string]int)
+	OperationFailed(event OperationEvent, err error)
+}
+
+type mappingNameKey struct{}
+
+// WithMappingName names the mapping applied with ctx, so that observers can tell
+// which mapping is dropping or failing messages.
+func WithMappingName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, mappingNameKey{}, name)
+}
+
+func operationEvent(ctx context.Context, direction Direction, index int, op string) OperationEvent {
+	name, _ := ctx.Value(mappingNameKey{}).(string)
+	return OperationEvent{Mapping: name, Direction: direction, Index: index, Op: op}
+}
+
+func observeUpOperation(ctx context.Context, observer Observer, index int, message *flow.UpMessage, operation *ontology.UpOperationInterface, apply func() (*flow.UpMessage, error)) (*flow.UpMessage, error) {
+	if observer == nil {
+		return apply()
+	}
+	var event = operationEvent(ctx, UP_Direction, index, (*operation).ValidUpOperation())
+	observer.OperationStarted(event)
+	var start = time.Now()
+	retMessage, err := apply()
+	observer.OperationEnded(event, time.Since(start))
+	switch {
+	case (err != nil || retMessage == nil) && alreadyReported(ctx):
+	case err != nil:
+		observer.OperationFailed(event, err)
+		reported(ctx)
+	case retMessage == nil:
+		observer.MessageDropped(event)
+		reported(ctx)
+	default:
+		if recordsPerPoint := producedPoints(message, retMessage); len(recordsPerPoint) > 0 {
+			observer.PointsProduced(event, recordsPerPoint)
+		}
+	}
+	return retMessage, err
+}
+
+func observeDownOperation(ctx context.Context, observer Observer, index int, operation *ontology.DownOperationInterface, apply func() (*flow.DownMessage, error)) (*flow.DownMessage, error) {
+	if observer == nil {
+		return apply()
+	}
+	var event = operationEvent(ctx, DOWN_Direction, index, (*operation).ValidDownOperation())
+	observer.OperationStarted(event)
+	var start = time.Now()
+	retMessage, err := apply()
+	observer.OperationEnded(event, time.Since(start))
+	switch {
+	case (err != nil || retMessage == nil) && alreadyReported(ctx):
+	case err != nil:
+		observer.OperationFailed(event, err)
+		reported(ctx)
+	case retMessage == nil:
+		observer.MessageDropped(event)
+		reported(ctx)
+	}
+	return retMessage, err
+}
+
+// alreadyReported tells whether an operation nested in the one ending reported
+// the drop or failure of the message already.
+func alreadyReported(ctx context.Context) bool {
+	var outcome = operationScopeFrom(ctx).outcome
+	return outcome != nil && outcome.reported
+}
+
+func reported(ctx context.Context) {
+	if outcome := operationScopeFrom(ctx).outcome; outcome != nil {
+		outcome.reported = true
+	}
+}
+
+// observeFailure reports an operation that failed before being applied, such as
+// an operation unknown to the factory.
+func observeFailure(ctx context.Context, observer Observer, direction Direction, index int, op string, err error) {
+	if observer != nil {
+		observer.OperationFailed(operationEvent(ctx, direction, index, op), err)
+	}
+}
+
+// producedPoints counts the records of the points that are new or changed in after.
+func producedPoints(before *flow.UpMessage, after *flow.UpMessage) map[string]int {
+	var recordsPerPoint = make(map[string]int)
+	for key, point := range after.Points {
+		if previous, ok := before.Points[key]; ok && reflect.DeepEqual(previous, point) {
+			continue
+		}
+		recordsPerPoint[key] = len(point.Records)
+	}
+	return recordsPerPoint
+}
+
+// errorCode is the label of err in metrics, the code of a util.MappingError or the
+// kind of context error.
+func errorCode(err error) string {
+	var mappingError *util.MappingError
+	switch {
+	case errors.As(err, &mappingError):
+		return string(mappingError.Code)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadlineExceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,67 @@
+package operations
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"strings"
+	"testing"
+)
+
+func upLabels(mapping string, op string) map[string]string {
+	return map[string]string{"mapping": mapping, "direction": "up", "op": op}
+}
+
+func Test_should_count_operations_and_points_produced(t *testing.T) {
+	// Given
+	observer := NewCounterObserver()
+	service := OperationService{Factory: OperationFactory{}, Observer: observer}
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	ctx := WithMappingName(context.Background(), "temperatureSensor")
+	// When
+	_, err := service.ApplyUpOperationsContext(ctx, &inputUpMessage, operations)
+	// Then
+	assert.Nil(t, err)
+	var labels = upLabels("temperatureSensor", "extractPoints")
+	assert.Equal(t, 1.0, observer.Counter(OperationsStartedMetric, labels))
+	assert.Equal(t, 1.0, observer.Counter(OperationsEndedMetric, labels))
+	labels["point"] = "temperature"
+	assert.Equal(t, 1.0, observer.Counter(PointsProducedMetric, labels))
+	assert.Equal(t, 1.0, observer.Counter(RecordsProducedMetric, labels))
+}
+
+func Test_should_count_messages_dropped_by_filter(t *testing.T) {
+	// Given
+	observer := NewCounterObserver()
+	service := OperationService{Factory: OperationFactory{}, Observer: observer}
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceLocation: true}
+	operations := &OperationsUpSerDer{Operations: []ontology.UpOperationInterface{filterOpr}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	outputUpMessage, err := service.ApplyUpOperations(&inputUpMessage, operations)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, outputUpMessage)
+	assert.Equal(t, 1.0, observer.Counter(MessagesDroppedMetric, upLabels("", "filter")))
+}
+
+func Test_should_count_errors_by_code(t *testing.T) {
+	// Given
+	observer := NewCounterObserver()
+	operations := buildUpOperationsTemperature("{{packet.message.measures[?id == 'temperature'].value}}")
+	pipeline, _ := (&OperationService{Factory: OperationFactory{}, Observer: observer}).CompileUpOperations(operations)
+	inputUpMessage := buildInputUpMessage("length_mismatch_value_event_time.json")
+	// When
+	_, err := pipeline.Apply(&inputUpMessage)
+	// Then
+	assert.NotNil(t, err)
+	var labels = upLabels("", "extractPoints")
+	labels["code"] = string(util.CARDINALITY_MISMATCH_ErrorCode)
+	var exposition bytes.Buffer
+	_, _ = observer.WriteTo(&exposition)
+	assert.Equal(t, 1.0, observer.Counter(OperationErrorsMetric, labels))
+	assert.True(t, strings.Contains(exposition.String(), `ontology_mapping_operation_errors_total{code="cardinalityMismatch",direction="up",mapping="",op="extractPoints"} 1`))
+}
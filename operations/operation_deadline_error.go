@@ -0,0 +1,26 @@
+package operations
+
+import (
+	"fmt"
+	"time"
+)
+
+// OperationDeadlineError is returned when an operation overruns its time budget
+// or the deadline of the caller's context. It wraps context.DeadlineExceeded.
+type OperationDeadlineError struct {
+	Index  int
+	Op     string
+	Budget time.Duration
+	Err    error
+}
+
+func (deadlineError *OperationDeadlineError) Error() string {
+	if deadlineError.Budget > 0 {
+		return fmt.Sprintf("operation %d '%s' exceeded its time budget of %s: %v", deadlineError.Index, deadlineError.Op, deadlineError.Budget, deadlineError.Err)
+	}
+	return fmt.Sprintf("operation %d '%s' exceeded the deadline: %v", deadlineError.Index, deadlineError.Op, deadlineError.Err)
+}
+
+func (deadlineError *OperationDeadlineError) Unwrap() error {
+	return deadlineError.Err
+}
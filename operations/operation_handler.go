@@ -1,9 +1,42 @@
 package operations
 
+import "context"
 import "ontology-mapping-go-lib/models/ontology"
 import "ontology-mapping-go-lib/models/flow"
+import "ontology-mapping-go-lib/util"
 
 type OperationHandler interface {
 	ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error)
 	ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error)
 }
+
+// ContextOperationHandler is implemented by handlers that can stop an operation
+// in progress when ctx is cancelled or its deadline passes. Handlers that only
+// implement OperationHandler are checked against ctx before they run.
+type ContextOperationHandler interface {
+	OperationHandler
+	ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error)
+	ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error)
+}
+
+// DropExplainer is implemented by handlers that can filter a message out, to tell
+// in explain mode why a message was dropped.
+type DropExplainer interface {
+	ExplainUpDrop(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) string
+	ExplainDownDrop(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) string
+}
+
+// ExpressionLister is implemented by handlers whose operations hold JMESPath
+// templates, so that they can be compiled before any message is processed.
+type ExpressionLister interface {
+	UpExpressions(upOperation ontology.UpOperationInterface) []string
+	DownExpressions(downOperation ontology.DownOperationInterface) []string
+}
+
+// OperationValidator is implemented by handlers that can check an operation, its
+// expressions included, before any message is processed. Handlers without it only
+// get the expressions listed by ExpressionLister checked.
+type OperationValidator interface {
+	ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError
+	ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError
+}
@@ -0,0 +1,130 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"reflect"
+	"sync"
+)
+
+// UpModelFactory returns a new model to decode an up operation into, usually a
+// pointer to a struct such as &ontology.UpExtractPoints{}. The decoded value is
+// stored dereferenced, which is the form handlers receive.
+type UpModelFactory func() ontology.UpOperationInterface
+
+type DownModelFactory func() ontology.DownOperationInterface
+
+type HandlerFactory func() OperationHandler
+
+type upRegistration struct {
+	model   UpModelFactory
+	handler HandlerFactory
+}
+
+type downRegistration struct {
+	model   DownModelFactory
+	handler HandlerFactory
+}
+
+var registryMutex sync.RWMutex
+var upRegistry = make(map[string]upRegistration)
+var downRegistry = make(map[string]downRegistration)
+
+// RegisterUpOperation makes the up operation name known to OperationsUpSerDer and
+// OperationFactory. The model's ValidUpOperation must return name, as the factory
+// looks the handler up by that name.
+func RegisterUpOperation(name string, model UpModelFactory, handler HandlerFactory) error {
+	if op := model().ValidUpOperation(); op != name {
+		return fmt.Errorf("up operation '%s' has a model of operation '%s'", name, op)
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := upRegistry[name]; ok {
+		return fmt.Errorf("up operation '%s' is already registered", name)
+	}
+	upRegistry[name] = upRegistration{model: model, handler: handler}
+	return nil
+}
+
+func RegisterDownOperation(name string, model DownModelFactory, handler HandlerFactory) error {
+	if op := model().ValidDownOperation(); op != name {
+		return fmt.Errorf("down operation '%s' has a model of operation '%s'", name, op)
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := downRegistry[name]; ok {
+		return fmt.Errorf("down operation '%s' is already registered", name)
+	}
+	downRegistry[name] = downRegistration{model: model, handler: handler}
+	return nil
+}
+
+func MustRegisterUpOperation(name string, model UpModelFactory, handler HandlerFactory) {
+	if err := RegisterUpOperation(name, model, handler); err != nil {
+		panic(err)
+	}
+}
+
+func MustRegisterDownOperation(name string, model DownModelFactory, handler HandlerFactory) {
+	if err := RegisterDownOperation(name, model, handler); err != nil {
+		panic(err)
+	}
+}
+
+func lookupUpOperation(name string) (upRegistration, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	registration, ok := upRegistry[name]
+	return registration, ok
+}
+
+func lookupDownOperation(name string) (downRegistration, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	registration, ok := downRegistry[name]
+	return registration, ok
+}
+
+func decodeUpOperation(index int, name string, raw json.RawMessage) (ontology.UpOperationInterface, error) {
+	registration, ok := lookupUpOperation(name)
+	if !ok {
+		return nil, &util.MappingError{Code: util.UNKNOWN_OPERATION_ErrorCode, Index: index, Op: name, Message: "unknown operation type"}
+	}
+	var model = registration.model()
+	if err := json.Unmarshal(raw, model); err != nil {
+		return nil, err
+	}
+	if decoder, ok := model.(ontology.UpOperationsDecoder); ok {
+		if err := decoder.DecodeUpOperations(decodeUpOperations); err != nil {
+			return nil, util.WithOperation(err, index, name)
+		}
+	}
+	return dereference(model).(ontology.UpOperationInterface), nil
+}
+
+func decodeDownOperation(index int, name string, raw json.RawMessage) (ontology.DownOperationInterface, error) {
+	registration, ok := lookupDownOperation(name)
+	if !ok {
+		return nil, &util.MappingError{Code: util.UNKNOWN_OPERATION_ErrorCode, Index: index, Op: name, Message: "unknown operation type"}
+	}
+	var model = registration.model()
+	if err := json.Unmarshal(raw, model); err != nil {
+		return nil, err
+	}
+	if decoder, ok := model.(ontology.DownOperationsDecoder); ok {
+		if err := decoder.DecodeDownOperations(decodeDownOperations); err != nil {
+			return nil, util.WithOperation(err, index, name)
+		}
+	}
+	return dereference(model).(ontology.DownOperationInterface), nil
+}
+
+func dereference(model interface{}) interface{} {
+	var value = reflect.ValueOf(model)
+	if value.Kind() == reflect.Ptr {
+		return value.Elem().Interface()
+	}
+	return model
+}
@@ -0,0 +1,89 @@
+package operations
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+)
+
+type upTagOperation struct {
+	Tag string `json:"tag"`
+	ontology.UpOperation
+}
+
+func (tag upTagOperation) ValidUpOperation() string {
+	return "testTag"
+}
+
+type upTagOperationHandler struct {
+}
+
+func (tagHandler *upTagOperationHandler) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	retMessage.Thing.Tags = append(retMessage.Thing.Tags, (*upOperation).(upTagOperation).Tag)
+	return retMessage, nil
+}
+
+func (tagHandler *upTagOperationHandler) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (tagHandler *upTagOperationHandler) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	return []string{upOperation.(upTagOperation).Tag}
+}
+
+func (tagHandler *upTagOperationHandler) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	return nil
+}
+
+func init() {
+	MustRegisterUpOperation("testTag", func() ontology.UpOperationInterface { return &upTagOperation{} }, func() OperationHandler { return &upTagOperationHandler{} })
+}
+
+func Test_should_deserialize_and_apply_registered_up_operation(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	err := json.Unmarshal([]byte(`{"operations":[
+		{"op":"extractPoints","points":{"temperature":{"value":"{{packet.message.temperature}}","eventTime":"{{time}}","type":"double"}}},
+		{"op":"testTag","tag":"mapped"}]}`), &operations)
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	outputUpMessage, applyErr := pipelineService.ApplyUpOperations(&inputUpMessage, &operations)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, applyErr)
+	assert.Equal(t, upTagOperation{Tag: "mapped", UpOperation: ontology.UpOperation{Op: "testTag"}}, operations.Operations[1])
+	assert.Equal(t, []string{"mapped"}, outputUpMessage.Thing.Tags)
+	assert.Equal(t, 22.6, outputUpMessage.Points["temperature"].Records[0].Value)
+}
+
+func Test_should_reject_operation_registered_twice(t *testing.T) {
+	// When
+	err := RegisterUpOperation("extractPoints", func() ontology.UpOperationInterface { return &ontology.UpExtractPoints{} }, func() OperationHandler { return &UpExtractPointsOperation{} })
+	// Then
+	assert.EqualError(t, err, "up operation 'extractPoints' is already registered")
+}
+
+func Test_should_reject_operation_registered_with_model_of_other_operation(t *testing.T) {
+	// When
+	upErr := RegisterUpOperation("testExtract", func() ontology.UpOperationInterface { return &ontology.UpExtractPoints{} }, func() OperationHandler { return &UpExtractPointsOperation{} })
+	downErr := RegisterDownOperation("testCommand", func() ontology.DownOperationInterface { return &ontology.DownUpdateCommand{} }, func() OperationHandler { return &DownUpdateCommandOperation{} })
+	// Then
+	assert.EqualError(t, upErr, "up operation 'testExtract' has a model of operation 'extractPoints'")
+	assert.EqualError(t, downErr, "down operation 'testCommand' has a model of operation 'updateCommand'")
+	_, registered := lookupUpOperation("testExtract")
+	assert.False(t, registered)
+}
+
+func Test_should_compile_expressions_listed_by_registered_handler(t *testing.T) {
+	// Given
+	var tagOpr ontology.UpOperationInterface = upTagOperation{Tag: "{{thing.[key}}"}
+	// When
+	_, err := pipelineService.CompileUpOperations(&OperationsUpSerDer{Operations: []ontology.UpOperationInterface{tagOpr}})
+	// Then
+	assert.True(t, errors.Is(err, util.ErrInvalidExpression))
+}
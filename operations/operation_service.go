@@ -1,28 +1,47 @@
 package operations
 
-import "ontology-mapping-go-lib/models/flow"
+import (
+	"context"
+	"errors"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"time"
+)
 
 type OperationService struct {
 	Factory OperationFactory
+	// OperationTimeout is the time budget of every single operation, zero means
+	// operations are only bound by the context given by the caller.
+	OperationTimeout time.Duration
+	// Observer, when set, is notified of every operation applied.
+	Observer Observer
 }
 
 func (operationService *OperationService) ApplyUpOperations(message *flow.UpMessage, operations *OperationsUpSerDer) (*flow.UpMessage, error) {
+	return operationService.ApplyUpOperationsContext(context.Background(), message, operations)
+}
+
+func (operationService *OperationService) ApplyUpOperationsContext(ctx context.Context, message *flow.UpMessage, operations *OperationsUpSerDer) (*flow.UpMessage, error) {
 	if message == nil {
 		return nil, nil
 	}
 	var err error
 	var handler OperationHandler
+	ctx = withOperationScope(ctx, operationService.Factory, operationService.Observer)
 	var retMessage = new(flow.UpMessage)
 	retMessage = message
-	for _, operation := range operations.Operations {
+	for index, operation := range operations.Operations {
 		if retMessage == nil {
 			return nil, nil
 		}
 		handler, err = operationService.Factory.BuildUp(operation)
 		if err != nil {
+			err = util.WithOperation(err, index, operation.ValidUpOperation())
+			observeFailure(ctx, operationService.Observer, UP_Direction, index, operation.ValidUpOperation(), err)
 			return nil, err
 		}
-		retMessage, err = handler.ApplyUpOperation(retMessage, &operation)
+		retMessage, err = applyUpOperation(ctx, operationService.OperationTimeout, operationService.Observer, index, handler, retMessage, &operation)
 		if err != nil {
 			return nil, err
 		}
@@ -31,25 +50,125 @@ func (operationService *OperationService) ApplyUpOperations(message *flow.UpMess
 }
 
 func (operationService *OperationService) ApplyDownOperations(message *flow.DownMessage, operations *OperationsDownSerDer) (*flow.DownMessage, error) {
+	return operationService.ApplyDownOperationsContext(context.Background(), message, operations)
+}
+
+func (operationService *OperationService) ApplyDownOperationsContext(ctx context.Context, message *flow.DownMessage, operations *OperationsDownSerDer) (*flow.DownMessage, error) {
 	if message == nil {
 		return nil, nil
 	}
 	var err error
 	var handler OperationHandler
+	ctx = withOperationScope(ctx, operationService.Factory, operationService.Observer)
 	var retMessage = new(flow.DownMessage)
 	retMessage = message
-	for _, operation := range operations.Operations {
+	for index, operation := range operations.Operations {
 		if retMessage == nil {
 			return nil, nil
 		}
 		handler, err = operationService.Factory.BuildDown(operation)
 		if err != nil {
+			err = util.WithOperation(err, index, operation.ValidDownOperation())
+			observeFailure(ctx, operationService.Observer, DOWN_Direction, index, operation.ValidDownOperation(), err)
 			return nil, err
 		}
-		retMessage, err = handler.ApplyDownOperation(retMessage, &operation)
+		retMessage, err = applyDownOperation(ctx, operationService.OperationTimeout, operationService.Observer, index, handler, retMessage, &operation)
 		if err != nil {
 			return nil, err
 		}
 	}
 	return retMessage, nil
 }
+
+type operationScopeKey struct{}
+
+// operationScope is what operations nesting others, such as when, need to apply
+// them as the service or the pipeline applying them would, along with the JSON
+// view of the message being mapped.
+type operationScope struct {
+	factory  OperationFactory
+	observer Observer
+	view     *messageView
+	outcome  *observedOutcome
+}
+
+// observedOutcome records that the drop or failure of the message was reported
+// to the observer, by the nested operation that caused it.
+type observedOutcome struct {
+	reported bool
+}
+
+// withOperationScope starts the scope of the operations applied to one message.
+func withOperationScope(ctx context.Context, factory OperationFactory, observer Observer) context.Context {
+	return context.WithValue(ctx, operationScopeKey{}, operationScope{factory: factory, observer: observer, view: new(messageView), outcome: new(observedOutcome)})
+}
+
+// operationScopeFrom returns the scope of ctx, a zero factory, no observer and no
+// cached view when the operations are applied on their own.
+func operationScopeFrom(ctx context.Context) operationScope {
+	scope, _ := ctx.Value(operationScopeKey{}).(operationScope)
+	return scope
+}
+
+func applyUpOperation(ctx context.Context, budget time.Duration, observer Observer, index int, handler OperationHandler, message *flow.UpMessage, operation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return observeUpOperation(ctx, observer, index, message, operation, func() (*flow.UpMessage, error) {
+		var cancel context.CancelFunc
+		var budgetCtx = ctx
+		if budget > 0 {
+			budgetCtx, cancel = context.WithTimeout(ctx, budget)
+			defer cancel()
+		}
+		if err := budgetCtx.Err(); err != nil {
+			return nil, operationError(err, index, (*operation).ValidUpOperation(), budget)
+		}
+		var retMessage *flow.UpMessage
+		var err error
+		if contextHandler, ok := handler.(ContextOperationHandler); ok {
+			retMessage, err = contextHandler.ApplyUpOperationContext(budgetCtx, message, operation)
+		} else {
+			retMessage, err = handler.ApplyUpOperation(message, operation)
+		}
+		if err != nil {
+			return nil, operationError(err, index, (*operation).ValidUpOperation(), budget)
+		}
+		return retMessage, nil
+	})
+}
+
+func applyDownOperation(ctx context.Context, budget time.Duration, observer Observer, index int, handler OperationHandler, message *flow.DownMessage, operation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return observeDownOperation(ctx, observer, index, operation, func() (*flow.DownMessage, error) {
+		var cancel context.CancelFunc
+		var budgetCtx = ctx
+		if budget > 0 {
+			budgetCtx, cancel = context.WithTimeout(ctx, budget)
+			defer cancel()
+		}
+		if err := budgetCtx.Err(); err != nil {
+			return nil, operationError(err, index, (*operation).ValidDownOperation(), budget)
+		}
+		var retMessage *flow.DownMessage
+		var err error
+		if contextHandler, ok := handler.(ContextOperationHandler); ok {
+			retMessage, err = contextHandler.ApplyDownOperationContext(budgetCtx, message, operation)
+		} else {
+			retMessage, err = handler.ApplyDownOperation(message, operation)
+		}
+		if err != nil {
+			return nil, operationError(err, index, (*operation).ValidDownOperation(), budget)
+		}
+		return retMessage, nil
+	})
+}
+
+// operationError names the operation that failed: a passed deadline becomes an
+// OperationDeadlineError, a cancellation is returned as is and any other error
+// becomes a util.MappingError.
+func operationError(err error, index int, op string, budget time.Duration) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &OperationDeadlineError{Index: index, Op: op, Budget: budget, Err: err}
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return util.WithOperation(err, index, op)
+}
@@ -0,0 +1,227 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/util"
+	"runtime"
+	"sync"
+)
+
+// UpMessageResult is the outcome of one message of an up batch, Message is nil
+// when the message was filtered out or when Err is set.
+type UpMessageResult struct {
+	Message *flow.UpMessage
+	Err     error
+}
+
+// DownMessageResult is the outcome of one message of a down batch.
+type DownMessageResult struct {
+	Message *flow.DownMessage
+	Err     error
+}
+
+// ApplyUpOperationsBatch applies the operations to every message on at most
+// workers goroutines (runtime.NumCPU() when workers <= 0) and returns the
+// results in input order.
+func (operationService *OperationService) ApplyUpOperationsBatch(messages []flow.UpMessage, operations *OperationsUpSerDer, workers int) []UpMessageResult {
+	return operationService.ApplyUpOperationsBatchContext(context.Background(), messages, operations, workers)
+}
+
+// ApplyUpOperationsBatchContext applies every message within ctx, the messages
+// left once ctx is done fail with its error.
+func (operationService *OperationService) ApplyUpOperationsBatchContext(ctx context.Context, messages []flow.UpMessage, operations *OperationsUpSerDer, workers int) []UpMessageResult {
+	var results = make([]UpMessageResult, len(messages))
+	runIndexed(len(messages), workers, func(i int) {
+		results[i].Message, results[i].Err = operationService.applyUpRecovered(ctx, &messages[i], operations)
+	})
+	return results
+}
+
+func (operationService *OperationService) ApplyDownOperationsBatch(messages []flow.DownMessage, operations *OperationsDownSerDer, workers int) []DownMessageResult {
+	return operationService.ApplyDownOperationsBatchContext(context.Background(), messages, operations, workers)
+}
+
+func (operationService *OperationService) ApplyDownOperationsBatchContext(ctx context.Context, messages []flow.DownMessage, operations *OperationsDownSerDer, workers int) []DownMessageResult {
+	var results = make([]DownMessageResult, len(messages))
+	runIndexed(len(messages), workers, func(i int) {
+		results[i].Message, results[i].Err = operationService.applyDownRecovered(ctx, &messages[i], operations)
+	})
+	return results
+}
+
+// ApplyUpOperationsChannel is the streaming form of ApplyUpOperationsBatch: results
+// are emitted in the order messages were received, and the returned channel is
+// closed once messages is closed and drained.
+func (operationService *OperationService) ApplyUpOperationsChannel(messages <-chan flow.UpMessage, operations *OperationsUpSerDer, workers int) <-chan UpMessageResult {
+	return operationService.ApplyUpOperationsChannelContext(context.Background(), messages, operations, workers)
+}
+
+// ApplyUpOperationsChannelContext stops reading messages once ctx is done, drops
+// the results not received yet and closes the returned channel, so that a
+// consumer may cancel ctx and stop reading.
+func (operationService *OperationService) ApplyUpOperationsChannelContext(ctx context.Context, messages <-chan flow.UpMessage, operations *OperationsUpSerDer, workers int) <-chan UpMessageResult {
+	var results = make(chan UpMessageResult)
+	var pending = make(chan chan UpMessageResult, workerCount(workers))
+	var slots = make(chan struct{}, workerCount(workers))
+	go func() {
+		defer close(pending)
+		for {
+			var message flow.UpMessage
+			var ok bool
+			select {
+			case message, ok = <-messages:
+			case <-ctx.Done():
+				return
+			}
+			if !ok || !acquire(ctx, slots) {
+				return
+			}
+			var future = make(chan UpMessageResult, 1)
+			select {
+			case pending <- future:
+			case <-ctx.Done():
+				return
+			}
+			go func(message flow.UpMessage) {
+				defer func() { <-slots }()
+				retMessage, err := operationService.applyUpRecovered(ctx, &message, operations)
+				future <- UpMessageResult{Message: retMessage, Err: err}
+			}(message)
+		}
+	}()
+	go func() {
+		defer close(results)
+		for future := range pending {
+			select {
+			case results <- <-future:
+			case <-ctx.Done():
+				drain(pending)
+				return
+			}
+		}
+	}()
+	return results
+}
+
+func (operationService *OperationService) ApplyDownOperationsChannel(messages <-chan flow.DownMessage, operations *OperationsDownSerDer, workers int) <-chan DownMessageResult {
+	return operationService.ApplyDownOperationsChannelContext(context.Background(), messages, operations, workers)
+}
+
+func (operationService *OperationService) ApplyDownOperationsChannelContext(ctx context.Context, messages <-chan flow.DownMessage, operations *OperationsDownSerDer, workers int) <-chan DownMessageResult {
+	var results = make(chan DownMessageResult)
+	var pending = make(chan chan DownMessageResult, workerCount(workers))
+	var slots = make(chan struct{}, workerCount(workers))
+	go func() {
+		defer close(pending)
+		for {
+			var message flow.DownMessage
+			var ok bool
+			select {
+			case message, ok = <-messages:
+			case <-ctx.Done():
+				return
+			}
+			if !ok || !acquire(ctx, slots) {
+				return
+			}
+			var future = make(chan DownMessageResult, 1)
+			select {
+			case pending <- future:
+			case <-ctx.Done():
+				return
+			}
+			go func(message flow.DownMessage) {
+				defer func() { <-slots }()
+				retMessage, err := operationService.applyDownRecovered(ctx, &message, operations)
+				future <- DownMessageResult{Message: retMessage, Err: err}
+			}(message)
+		}
+	}()
+	go func() {
+		defer close(results)
+		for future := range pending {
+			select {
+			case results <- <-future:
+			case <-ctx.Done():
+				drainDown(pending)
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// acquire takes a worker slot, unless ctx is done first.
+func acquire(ctx context.Context, slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// drain lets the reading goroutine close pending, the workers writing into
+// buffered futures end on their own.
+func drain(pending <-chan chan UpMessageResult) {
+	for range pending {
+	}
+}
+
+func drainDown(pending <-chan chan DownMessageResult) {
+	for range pending {
+	}
+}
+
+// applyUpRecovered is ApplyUpOperationsContext turning a panic into the error of
+// the message, so that one message cannot stop the whole batch.
+func (operationService *OperationService) applyUpRecovered(ctx context.Context, message *flow.UpMessage, operations *OperationsUpSerDer) (retMessage *flow.UpMessage, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			retMessage, err = nil, recoveredError(recovered)
+		}
+	}()
+	return operationService.ApplyUpOperationsContext(ctx, message, operations)
+}
+
+func (operationService *OperationService) applyDownRecovered(ctx context.Context, message *flow.DownMessage, operations *OperationsDownSerDer) (retMessage *flow.DownMessage, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			retMessage, err = nil, recoveredError(recovered)
+		}
+	}()
+	return operationService.ApplyDownOperationsContext(ctx, message, operations)
+}
+
+func recoveredError(recovered interface{}) error {
+	var cause, _ = recovered.(error)
+	return &util.MappingError{Code: util.OPERATION_FAILED_ErrorCode, Message: fmt.Sprintf("panic while applying operations: %v", recovered), Err: cause}
+}
+
+func runIndexed(count int, workers int, apply func(i int)) {
+	var jobs = make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workerCount(workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				apply(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func workerCount(workers int) int {
+	if workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return workers
+}
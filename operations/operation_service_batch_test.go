@@ -0,0 +1,172 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"runtime"
+	"testing"
+	"time"
+)
+
+var batchService = OperationService{Factory: OperationFactory{}}
+
+func buildBatchUpMessages(count int) []flow.UpMessage {
+	var messages []flow.UpMessage
+	for i := 0; i < count; i++ {
+		message := buildInputUpMessage("include_existing_points.json")
+		message.Packet.Message = map[string]interface{}{"temperature": float64(i)}
+		if i%3 == 0 {
+			message.Packet.Message = map[string]interface{}{"temperature": []interface{}{1.0, 2.0}}
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+func Test_should_apply_up_operations_batch_in_input_order(t *testing.T) {
+	// Given
+	messages := buildBatchUpMessages(50)
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	// When
+	results := batchService.ApplyUpOperationsBatch(messages, operations, 4)
+	// Then
+	assert.Equal(t, 50, len(results))
+	for i, result := range results {
+		if i%3 == 0 {
+			assert.NotNil(t, result.Err)
+			assert.Nil(t, result.Message)
+		} else {
+			assert.Nil(t, result.Err)
+			assert.Equal(t, float64(i), result.Message.Points["temperature"].Records[0].Value)
+		}
+	}
+}
+
+func Test_should_apply_up_operations_channel_in_input_order(t *testing.T) {
+	// Given
+	messages := buildBatchUpMessages(50)
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	input := make(chan flow.UpMessage)
+	go func() {
+		for _, message := range messages {
+			input <- message
+		}
+		close(input)
+	}()
+	// When
+	var results []UpMessageResult
+	for result := range batchService.ApplyUpOperationsChannel(input, operations, 0) {
+		results = append(results, result)
+	}
+	// Then
+	assert.Equal(t, batchService.ApplyUpOperationsBatch(messages, operations, 1), results)
+}
+
+func Test_should_release_channel_workers_when_consumer_abandons_results(t *testing.T) {
+	// Given
+	var goroutines = runtime.NumGoroutine()
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	input := make(chan flow.UpMessage, 20)
+	for _, message := range buildBatchUpMessages(20) {
+		input <- message
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	results := batchService.ApplyUpOperationsChannelContext(ctx, input, operations, 2)
+	// When
+	<-results
+	cancel()
+	// Then
+	var deadline = time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+	for range results {
+	}
+}
+
+func Test_should_fail_batch_messages_once_context_is_canceled(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// When
+	results := batchService.ApplyUpOperationsBatchContext(ctx, buildBatchUpMessages(5), operations, 2)
+	// Then
+	for _, result := range results {
+		assert.True(t, errors.Is(result.Err, context.Canceled))
+	}
+}
+
+func Test_should_apply_down_operations_batch_in_input_order(t *testing.T) {
+	// Given
+	var downOpr ontology.DownOperationInterface = ontology.DownUpdateCommand{Commands: map[string]ontology.UpdateCommand{
+		"default": {Input: "{{input.prop1}}"},
+	}}
+	operations := &OperationsDownSerDer{Operations: []ontology.DownOperationInterface{downOpr}}
+	var messages []flow.DownMessage
+	for i := 0; i < 20; i++ {
+		message := buildInputDownMessage("update_command_id.json")
+		message.Command.Input = map[string]interface{}{"prop1": float64(i)}
+		messages = append(messages, message)
+	}
+	input := make(chan flow.DownMessage, len(messages))
+	for _, message := range messages {
+		input <- message
+	}
+	close(input)
+	// When
+	results := batchService.ApplyDownOperationsBatch(messages, operations, 3)
+	var streamed []DownMessageResult
+	for result := range batchService.ApplyDownOperationsChannel(input, operations, 3) {
+		streamed = append(streamed, result)
+	}
+	// Then
+	for i, result := range results {
+		assert.Nil(t, result.Err)
+		assert.Equal(t, float64(i), result.Message.Command.Input)
+	}
+	assert.Equal(t, results, streamed)
+}
+
+func Test_should_report_panicking_message_as_its_error(t *testing.T) {
+	// Given
+	messages := buildBatchUpMessages(6)
+	for i := range messages {
+		if i%2 == 0 {
+			// the testTag handler panics on a message without thing
+			messages[i].Thing = nil
+		}
+	}
+	operations := &OperationsUpSerDer{Operations: []ontology.UpOperationInterface{upTagOperation{Tag: "mapped"}}}
+	input := make(chan flow.UpMessage)
+	go func() {
+		for _, message := range messages {
+			input <- message
+		}
+		close(input)
+	}()
+	// When
+	results := batchService.ApplyUpOperationsBatch(messages, operations, 2)
+	var streamed []UpMessageResult
+	for result := range batchService.ApplyUpOperationsChannel(input, operations, 2) {
+		streamed = append(streamed, result)
+	}
+	// Then
+	for _, batch := range [][]UpMessageResult{results, streamed} {
+		assert.Equal(t, 6, len(batch))
+		for i, result := range batch {
+			if i%2 == 0 {
+				assert.True(t, errors.Is(result.Err, util.ErrOperationFailed))
+				assert.Nil(t, result.Message)
+			} else {
+				assert.Nil(t, result.Err)
+				assert.Equal(t, []string{"mapped"}, result.Message.Thing.Tags)
+			}
+		}
+	}
+}
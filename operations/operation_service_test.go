@@ -0,0 +1,143 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+	"time"
+)
+
+func Test_should_apply_up_operations_with_context(t *testing.T) {
+	// Given
+	service := OperationService{Factory: OperationFactory{}, OperationTimeout: time.Minute}
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	// When
+	outputUpMessage, err := service.ApplyUpOperationsContext(ctx, &inputUpMessage, operations)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, 22.6, outputUpMessage.Points["temperature"].Records[0].Value)
+}
+
+func Test_should_return_canceled_error_when_context_is_canceled(t *testing.T) {
+	// Given
+	service := OperationService{Factory: OperationFactory{}}
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// When
+	outputUpMessage, err := service.ApplyUpOperationsContext(ctx, &inputUpMessage, operations)
+	// Then
+	assert.Nil(t, outputUpMessage)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func Test_should_not_evaluate_expression_once_context_is_canceled(t *testing.T) {
+	// Given
+	var recorder util.ExpressionRecorder
+	ctx, cancel := context.WithCancel(util.WithExpressionRecorder(context.Background(), &recorder))
+	var message interface{} = map[string]interface{}{"temperature": 22.6}
+	// When
+	value, err := util.RetrieveValuesContext(ctx, "{{temperature}}", &message)
+	cancel()
+	_, canceledErr := util.RetrieveValuesContext(ctx, "{{temperature}}", &message)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, 22.6, value)
+	assert.True(t, errors.Is(canceledErr, context.Canceled))
+	assert.Equal(t, 1, len(recorder.Evaluations()))
+}
+
+func Test_should_name_operation_that_exceeded_its_time_budget(t *testing.T) {
+	// Given
+	service := OperationService{Factory: OperationFactory{}, OperationTimeout: time.Nanosecond}
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceUplink: true}
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	operations.Operations = append([]ontology.UpOperationInterface{filterOpr}, operations.Operations...)
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	_, err := service.ApplyUpOperations(&inputUpMessage, operations)
+	// Then
+	var deadlineErr *OperationDeadlineError
+	assert.True(t, errors.As(err, &deadlineErr))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 0, deadlineErr.Index)
+	assert.Equal(t, "filter", deadlineErr.Op)
+	assert.Equal(t, time.Nanosecond, deadlineErr.Budget)
+}
+
+func Test_should_name_down_operation_that_exceeded_the_context_deadline(t *testing.T) {
+	// Given
+	service := OperationService{Factory: OperationFactory{}}
+	var downOpr ontology.DownOperationInterface = ontology.DownUpdateCommand{Commands: map[string]ontology.UpdateCommand{
+		"default": {Input: "{{input.prop1}}"},
+	}}
+	inputDownMessage := buildInputDownMessage("update_command_id.json")
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	// When
+	_, err := service.ApplyDownOperationsContext(ctx, &inputDownMessage, &OperationsDownSerDer{Operations: []ontology.DownOperationInterface{downOpr}})
+	// Then
+	var deadlineErr *OperationDeadlineError
+	assert.True(t, errors.As(err, &deadlineErr))
+	assert.Equal(t, "updateCommand", deadlineErr.Op)
+	assert.Equal(t, time.Duration(0), deadlineErr.Budget)
+}
+
+func Test_should_return_mapping_error_with_operation_point_and_expression(t *testing.T) {
+	// Given
+	service := OperationService{Factory: OperationFactory{}}
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceUplink: true}
+	operations := buildUpOperationsTemperature("{{packet.message.[temperature}}")
+	operations.Operations = append([]ontology.UpOperationInterface{filterOpr}, operations.Operations...)
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	_, err := service.ApplyUpOperations(&inputUpMessage, operations)
+	// Then
+	var mappingErr *util.MappingError
+	assert.True(t, errors.As(err, &mappingErr))
+	assert.True(t, errors.Is(err, util.ErrInvalidExpression))
+	assert.False(t, errors.Is(err, util.ErrCardinalityMismatch))
+	assert.Equal(t, 1, mappingErr.Index)
+	assert.Equal(t, "extractPoints", mappingErr.Op)
+	assert.Equal(t, "temperature", mappingErr.Key)
+	assert.Equal(t, "{{packet.message.[temperature}}", mappingErr.Expression)
+	assert.Equal(t, "invalidExpression", mappingErr.FlowErrorInfo().Code)
+	assert.Equal(t, mappingErr.Error(), mappingErr.OntologyErrorInfo().Message)
+}
+
+func Test_should_return_mapping_error_with_command_key(t *testing.T) {
+	// Given
+	service := OperationService{Factory: OperationFactory{}}
+	var downOpr ontology.DownOperationInterface = ontology.DownUpdateCommand{Commands: map[string]ontology.UpdateCommand{
+		"myDeviceCommand": {Input: "{{input.unknown}}"},
+	}}
+	inputDownMessage := buildInputDownMessage("update_command_id.json")
+	// When
+	_, err := service.ApplyDownOperations(&inputDownMessage, &OperationsDownSerDer{Operations: []ontology.DownOperationInterface{downOpr}})
+	// Then
+	var mappingErr *util.MappingError
+	assert.True(t, errors.As(err, &mappingErr))
+	assert.True(t, errors.Is(err, util.ErrUnexpectedResult))
+	assert.Equal(t, "operation 0 'updateCommand', key 'myDeviceCommand', expression '{{input.unknown}}': retrieved value is null or not a map", err.Error())
+}
+
+func Test_should_return_unknown_operation_error_when_deserializing(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	// When
+	err := json.Unmarshal([]byte(`{"operations":[{"op":"filter"},{"op":"unknown"}]}`), &operations)
+	// Then
+	var mappingErr *util.MappingError
+	assert.True(t, errors.As(err, &mappingErr))
+	assert.True(t, errors.Is(err, util.ErrUnknownOperation))
+	assert.Equal(t, 1, mappingErr.Index)
+	assert.Equal(t, "unknown", mappingErr.Op)
+}
@@ -0,0 +1,209 @@
+package operations
+
+import (
+	"fmt"
+	"math"
+	"ontology-mapping-go-lib/models/flow"
+	"sort"
+	"time"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type UpAggregatePointsOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("aggregatePoints", func() ontology.UpOperationInterface { return &ontology.UpAggregatePoints{} }, func() OperationHandler { return &UpAggregatePointsOperation{} })
+}
+
+func (aggregatePoints *UpAggregatePointsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	var aggregations = (*upOperation).(ontology.UpAggregatePoints).Points
+	var keys []string
+	for key := range aggregations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var newPoints = make(map[string]flow.Point)
+	for _, key := range keys {
+		source, ok := message.Points[aggregations[key].Source]
+		if !ok {
+			continue
+		}
+		point, ok, err := aggregatePoint(message, source, aggregations[key])
+		if err != nil {
+			return nil, util.WithKey(err, key)
+		}
+		if ok {
+			newPoints[key] = point
+		}
+	}
+	if len(newPoints) > 0 {
+		if retMessage.Points == nil {
+			retMessage.Points = make(map[string]flow.Point)
+		}
+		for key, point := range newPoints {
+			retMessage.Points[key] = point
+		}
+	}
+	return retMessage, nil
+}
+
+func (aggregatePoints *UpAggregatePointsOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (aggregatePoints *UpAggregatePointsOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var aggregations = upOperation.(ontology.UpAggregatePoints).Points
+	if len(aggregations) == 0 {
+		return []*util.MappingError{util.NewMappingError(util.EMPTY_POINTS_ErrorCode, "'points' must not be empty")}
+	}
+	var problems []*util.MappingError
+	for key, aggregation := range aggregations {
+		if err := validateAggregation(aggregation); err != nil {
+			problems = append(problems, &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Key: key, Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+func (aggregatePoints *UpAggregatePointsOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+func validateAggregation(aggregation ontology.AggregatePoint) error {
+	if len(aggregation.Source) == 0 {
+		return fmt.Errorf("'source' must not be empty")
+	}
+	switch aggregation.Function {
+	case ontology.MIN_AggregateFunction, ontology.MAX_AggregateFunction, ontology.MEAN_AggregateFunction, ontology.SUM_AggregateFunction,
+		ontology.COUNT_AggregateFunction, ontology.FIRST_AggregateFunction, ontology.LAST_AggregateFunction:
+	case ontology.PERCENTILE_AggregateFunction:
+		if aggregation.Percentile < 0 || aggregation.Percentile > 100 {
+			return fmt.Errorf("'percentile' %v must be between 0 and 100", aggregation.Percentile)
+		}
+	default:
+		return fmt.Errorf("unknown function '%s'", aggregation.Function)
+	}
+	switch aggregation.EventTime {
+	case "", ontology.FIRST_AggregateEventTime, ontology.LAST_AggregateEventTime, ontology.MESSAGE_AggregateEventTime:
+		return nil
+	default:
+		return fmt.Errorf("unknown eventTime '%s'", aggregation.EventTime)
+	}
+}
+
+// aggregatePoint computes the point derived from source, it reports false when
+// source has no value to aggregate but for count.
+func aggregatePoint(message *flow.UpMessage, source flow.Point, aggregation ontology.AggregatePoint) (flow.Point, bool, error) {
+	if err := validateAggregation(aggregation); err != nil {
+		return flow.Point{}, false, util.NewMappingError(util.INVALID_RULE_ErrorCode, err.Error())
+	}
+	var records []flow.Record
+	for _, record := range source.Records {
+		if record.Value != nil {
+			records = append(records, record)
+		}
+	}
+	// records are ordered by eventTime so that first and last do not depend on the
+	// order in which the device sent its samples
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].EventTime.Before(records[j].EventTime)
+	})
+	var point = flow.Point{OntologyId: aggregation.OntologyId, UnitId: aggregation.UnitId, Type_: flow.DOUBLE_Type}
+	if len(point.UnitId) == 0 && aggregation.Function != ontology.COUNT_AggregateFunction {
+		point.UnitId = source.UnitId
+	}
+	var eventTime time.Time
+	switch {
+	case aggregation.EventTime == ontology.MESSAGE_AggregateEventTime:
+		eventTime = message.Time
+	case len(records) == 0:
+		eventTime = message.Time
+	case aggregation.EventTime == ontology.FIRST_AggregateEventTime:
+		eventTime = records[0].EventTime
+	default:
+		eventTime = records[len(records)-1].EventTime
+	}
+	var value interface{}
+	switch aggregation.Function {
+	case ontology.COUNT_AggregateFunction:
+		point.Type_ = flow.INT64__Type
+		value = int64(len(records))
+	case ontology.FIRST_AggregateFunction, ontology.LAST_AggregateFunction:
+		if len(records) == 0 {
+			return point, false, nil
+		}
+		point.Type_ = source.Type_
+		value = records[len(records)-1].Value
+		if aggregation.Function == ontology.FIRST_AggregateFunction {
+			value = records[0].Value
+		}
+	default:
+		if len(records) == 0 {
+			return point, false, nil
+		}
+		values, err := recordDoubles(records)
+		if err != nil {
+			return point, false, err
+		}
+		value = aggregateDoubles(values, aggregation)
+	}
+	point.Records = []flow.Record{{Value: value, EventTime: eventTime}}
+	return point, true, nil
+}
+
+func recordDoubles(records []flow.Record) ([]float64, error) {
+	var values = make([]float64, len(records))
+	for i, record := range records {
+		value, err := util.ConvertValue(record.Value, flow.DOUBLE_Type)
+		if err != nil {
+			return nil, &util.MappingError{Code: util.CONVERSION_FAILED_ErrorCode,
+				Message: fmt.Sprintf("cannot aggregate value %v: %v", record.Value, err), Err: err}
+		}
+		values[i] = value.(float64)
+	}
+	return values, nil
+}
+
+func aggregateDoubles(values []float64, aggregation ontology.AggregatePoint) float64 {
+	switch aggregation.Function {
+	case ontology.MIN_AggregateFunction:
+		var min = values[0]
+		for _, value := range values[1:] {
+			min = math.Min(min, value)
+		}
+		return min
+	case ontology.MAX_AggregateFunction:
+		var max = values[0]
+		for _, value := range values[1:] {
+			max = math.Max(max, value)
+		}
+		return max
+	case ontology.SUM_AggregateFunction:
+		return sumDoubles(values)
+	case ontology.MEAN_AggregateFunction:
+		return sumDoubles(values) / float64(len(values))
+	default:
+		return percentile(values, aggregation.Percentile)
+	}
+}
+
+func sumDoubles(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum
+}
+
+// percentile interpolates linearly between the two closest ranks.
+func percentile(values []float64, rank float64) float64 {
+	var sorted = append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	var position = rank / 100 * float64(len(sorted)-1)
+	var lower = int(math.Floor(position))
+	var upper = int(math.Ceil(position))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+}
@@ -0,0 +1,98 @@
+package operations
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+	"time"
+)
+
+var aggregatePointsOperation = UpAggregatePointsOperation{}
+
+func buildUpMessageWithSamples() flow.UpMessage {
+	var messageTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	var records []flow.Record
+	for i, value := range []float64{21.0, 25.0, 19.0, 23.0} {
+		records = append(records, flow.Record{Value: value, EventTime: messageTime.Add(time.Duration(i-4) * time.Minute)})
+	}
+	// samples are not sent in chronological order
+	records[0], records[3] = records[3], records[0]
+	return flow.UpMessage{Time: messageTime, Type_: "deviceUplink", Points: map[string]flow.Point{
+		"temperature": {Type_: flow.DOUBLE_Type, UnitId: "Cel", Records: records},
+	}}
+}
+
+func aggregateValue(t *testing.T, aggregation ontology.AggregatePoint) flow.Record {
+	var aggregateOpr ontology.UpOperationInterface = ontology.UpAggregatePoints{Points: map[string]ontology.AggregatePoint{"result": aggregation}}
+	inputUpMessage := buildUpMessageWithSamples()
+	outputUpMessage, err := aggregatePointsOperation.ApplyUpOperation(&inputUpMessage, &aggregateOpr)
+	assert.Nil(t, err)
+	return outputUpMessage.Points["result"].Records[0]
+}
+
+func Test_should_aggregate_point_records(t *testing.T) {
+	// Given
+	inputUpMessage := buildUpMessageWithSamples()
+	// When & Then
+	assert.Equal(t, 19.0, aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "min"}).Value)
+	assert.Equal(t, 25.0, aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "max"}).Value)
+	assert.Equal(t, 22.0, aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "mean"}).Value)
+	assert.Equal(t, 88.0, aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "sum"}).Value)
+	assert.Equal(t, int64(4), aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "count"}).Value)
+	assert.Equal(t, 21.0, aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "first"}).Value)
+	assert.Equal(t, 23.0, aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "last"}).Value)
+	assert.InDelta(t, 23.8, aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "percentile", Percentile: 80}).Value, 1e-9)
+	assert.Equal(t, inputUpMessage.Time.Add(-4*time.Minute), aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "min", EventTime: "first"}).EventTime)
+	assert.Equal(t, inputUpMessage.Time.Add(-time.Minute), aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "min"}).EventTime)
+	assert.Equal(t, inputUpMessage.Time, aggregateValue(t, ontology.AggregatePoint{Source: "temperature", Function: "min", EventTime: "message"}).EventTime)
+}
+
+func Test_should_set_ontology_and_unit_of_aggregated_point(t *testing.T) {
+	// Given
+	var aggregateOpr ontology.UpOperationInterface = ontology.UpAggregatePoints{Points: map[string]ontology.AggregatePoint{
+		"temperatureMean":  {Source: "temperature", Function: "mean", OntologyId: "Temperature:mean"},
+		"temperatureCount": {Source: "temperature", Function: "count"},
+		"humidityMean":     {Source: "humidity", Function: "mean"},
+	}}
+	inputUpMessage := buildUpMessageWithSamples()
+	// When
+	outputUpMessage, err := aggregatePointsOperation.ApplyUpOperation(&inputUpMessage, &aggregateOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "Temperature:mean", outputUpMessage.Points["temperatureMean"].OntologyId)
+	assert.Equal(t, "Cel", outputUpMessage.Points["temperatureMean"].UnitId)
+	assert.Equal(t, flow.INT64__Type, outputUpMessage.Points["temperatureCount"].Type_)
+	assert.Equal(t, "", outputUpMessage.Points["temperatureCount"].UnitId)
+	assert.NotContains(t, outputUpMessage.Points, "humidityMean")
+	assert.Equal(t, 4, len(outputUpMessage.Points["temperature"].Records))
+}
+
+func Test_should_return_error_when_aggregated_value_is_not_a_number(t *testing.T) {
+	// Given
+	var aggregateOpr ontology.UpOperationInterface = ontology.UpAggregatePoints{Points: map[string]ontology.AggregatePoint{
+		"labelMax": {Source: "label", Function: "max"},
+	}}
+	inputUpMessage := buildUpMessageWithPoint("label", flow.STRING__Type, "open")
+	// When
+	_, err := aggregatePointsOperation.ApplyUpOperation(&inputUpMessage, &aggregateOpr)
+	// Then
+	assert.True(t, errors.Is(err, util.ErrConversionFailed))
+	assert.EqualError(t, err, "cannot aggregate value open: 'open' is not a number")
+}
+
+func Test_should_report_invalid_aggregation_on_validation(t *testing.T) {
+	// Given
+	var aggregateOpr ontology.UpOperationInterface = ontology.UpAggregatePoints{Points: map[string]ontology.AggregatePoint{
+		"p95":    {Source: "temperature", Function: "percentile", Percentile: 195},
+		"median": {Source: "temperature", Function: "median"},
+	}}
+	operations := OperationsUpSerDer{Operations: []ontology.UpOperationInterface{aggregateOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	assert.EqualError(t, err, "2 problem(s) found in mapping: operation 0 'aggregatePoints', key 'median': unknown function 'median'; "+
+		"operation 0 'aggregatePoints', key 'p95': 'percentile' 195 must be between 0 and 100")
+}
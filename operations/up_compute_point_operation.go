@@ -0,0 +1,143 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"sort"
+	"time"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type UpComputePointOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("computePoint", func() ontology.UpOperationInterface { return &ontology.UpComputePoint{} }, func() OperationHandler { return &UpComputePointOperation{} })
+}
+
+// ApplyUpOperation evaluates the expression at every eventTime shared by the
+// records of all the points it uses, or at the message time when it uses none. The
+// message is returned unchanged when one of these points is missing or when they
+// share no eventTime.
+func (computePoint *UpComputePointOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return computePoint.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
+
+func (computePoint *UpComputePointOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	var computeOperation = (*upOperation).(ontology.UpComputePoint)
+	expression, err := computePoint.compile(ctx, computeOperation.Expression)
+	if err != nil {
+		return nil, util.WithKey(err, computeOperation.Point)
+	}
+	var valuesByTime = make(map[time.Time]map[string]float64)
+	if len(expression.Variables()) == 0 {
+		valuesByTime[message.Time] = make(map[string]float64)
+	}
+	for index, name := range expression.Variables() {
+		point, ok := message.Points[name]
+		if !ok {
+			return retMessage, nil
+		}
+		var pointValues = make(map[time.Time]float64)
+		for _, record := range point.Records {
+			if record.Value == nil {
+				continue
+			}
+			value, err := util.ConvertValue(record.Value, flow.DOUBLE_Type)
+			if err != nil {
+				return nil, &util.MappingError{Code: util.CONVERSION_FAILED_ErrorCode, Key: computeOperation.Point,
+					Message: fmt.Sprintf("cannot compute with value %v of '%s': %v", record.Value, name, err), Err: err}
+			}
+			pointValues[record.EventTime.UTC()] = value.(float64)
+		}
+		valuesByTime = alignValues(valuesByTime, index == 0, name, pointValues)
+	}
+	var eventTimes []time.Time
+	for eventTime := range valuesByTime {
+		eventTimes = append(eventTimes, eventTime)
+	}
+	if len(eventTimes) == 0 {
+		return retMessage, nil
+	}
+	sort.Slice(eventTimes, func(i, j int) bool {
+		return eventTimes[i].Before(eventTimes[j])
+	})
+	var pointType = flow.PointType(computeOperation.Type_)
+	if len(pointType) == 0 {
+		pointType = flow.DOUBLE_Type
+	}
+	var records []flow.Record
+	for _, eventTime := range eventTimes {
+		result, err := expression.Evaluate(valuesByTime[eventTime])
+		if err != nil {
+			return nil, util.WithKey(err, computeOperation.Point)
+		}
+		value, err := util.ConvertValue(result, pointType)
+		if err != nil {
+			return nil, &util.MappingError{Code: util.CONVERSION_FAILED_ErrorCode, Key: computeOperation.Point,
+				Message: fmt.Sprintf("cannot convert result %v to %s: %v", result, pointType, err), Err: err}
+		}
+		records = append(records, flow.Record{Value: value, EventTime: eventTime})
+	}
+	if retMessage.Points == nil {
+		retMessage.Points = make(map[string]flow.Point)
+	}
+	retMessage.Points[computeOperation.Point] = flow.Point{OntologyId: computeOperation.OntologyId, UnitId: computeOperation.UnitId, Type_: pointType, Records: records}
+	return retMessage, nil
+}
+
+func (computePoint *UpComputePointOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (computePoint *UpComputePointOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (computePoint *UpComputePointOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var computeOperation = upOperation.(ontology.UpComputePoint)
+	var problems []*util.MappingError
+	if len(computeOperation.Point) == 0 {
+		problems = append(problems, util.NewMappingError(util.INVALID_RULE_ErrorCode, "'point' must not be empty"))
+	}
+	if _, err := computePoint.compile(context.Background(), computeOperation.Expression); err != nil {
+		problems = append(problems, util.WithKey(err, computeOperation.Point).(*util.MappingError))
+	}
+	return append(problems, validatePoint(computeOperation.Point, "", "", nil, computeOperation.Type_)...)
+}
+
+func (computePoint *UpComputePointOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+// compile returns the compiled expression of source, once per compiled pipeline.
+func (computePoint *UpComputePointOperation) compile(ctx context.Context, source string) (*util.ArithmeticExpression, error) {
+	expression, err := compiledRule(ctx, "computePoint", source, func() (interface{}, error) {
+		return util.CompileArithmetic(source)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expression.(*util.ArithmeticExpression), nil
+}
+
+// alignValues keeps the eventTimes of aligned that pointValues also has, adding
+// the value of name to each of them. The first point initializes the alignment.
+func alignValues(aligned map[time.Time]map[string]float64, first bool, name string, pointValues map[time.Time]float64) map[time.Time]map[string]float64 {
+	var result = make(map[time.Time]map[string]float64)
+	for eventTime, value := range pointValues {
+		variables, ok := aligned[eventTime]
+		if !ok && !first {
+			continue
+		}
+		if !ok {
+			variables = make(map[string]float64)
+		}
+		variables[name] = value
+		result[eventTime] = variables
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+	"time"
+)
+
+var computePointOperation = UpComputePointOperation{}
+
+func buildUpMessageWithRecords(points map[string][]flow.Record) flow.UpMessage {
+	var messageTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	var message = flow.UpMessage{Time: messageTime, Type_: "deviceUplink", Points: make(map[string]flow.Point)}
+	for key, records := range points {
+		message.Points[key] = flow.Point{Type_: flow.DOUBLE_Type, Records: records}
+	}
+	return message
+}
+
+func Test_should_compute_point_from_other_points(t *testing.T) {
+	// Given
+	var eventTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	var computeOpr ontology.UpOperationInterface = ontology.UpComputePoint{
+		Point:      "dewPoint",
+		Expression: "temperature - (100 - humidity) / 5",
+		OntologyId: "DewPoint",
+		UnitId:     "Cel",
+	}
+	inputUpMessage := buildUpMessageWithRecords(map[string][]flow.Record{
+		"temperature": {{Value: 20.0, EventTime: eventTime}},
+		"humidity":    {{Value: "50", EventTime: eventTime}},
+	})
+	// When
+	outputUpMessage, err := computePointOperation.ApplyUpOperation(&inputUpMessage, &computeOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, flow.Point{OntologyId: "DewPoint", UnitId: "Cel", Type_: flow.DOUBLE_Type, Records: []flow.Record{
+		{Value: 10.0, EventTime: eventTime},
+	}}, outputUpMessage.Points["dewPoint"])
+	assert.NotContains(t, inputUpMessage.Points, "dewPoint")
+}
+
+func Test_should_compile_compute_expression_once(t *testing.T) {
+	// Given
+	var eventTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	var computeOpr ontology.UpOperationInterface = ontology.UpComputePoint{Point: "doubled", Expression: "temperature * 2"}
+	var rules = new(compiledRules)
+	var ctx = withCompiledRules(context.Background(), rules)
+	var key = compiledRuleKey{kind: "computePoint", source: "temperature * 2"}
+	inputUpMessage := buildUpMessageWithRecords(map[string][]flow.Record{"temperature": {{Value: 20.0, EventTime: eventTime}}})
+	// When
+	_, err := computePointOperation.ApplyUpOperationContext(ctx, &inputUpMessage, &computeOpr)
+	compiled, _ := rules.rules.Load(key)
+	outputUpMessage, secondErr := computePointOperation.ApplyUpOperationContext(ctx, &inputUpMessage, &computeOpr)
+	recompiled, _ := rules.rules.Load(key)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, secondErr)
+	assert.NotNil(t, compiled)
+	assert.True(t, compiled == recompiled)
+	assert.Equal(t, 40.0, outputUpMessage.Points["doubled"].Records[0].Value)
+}
+
+func Test_should_compute_point_at_shared_event_times(t *testing.T) {
+	// Given
+	var eventTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	var computeOpr ontology.UpOperationInterface = ontology.UpComputePoint{
+		Point:      "totalEnergy",
+		Expression: "round(max(`index-hc`, 0) + `index-hp`)",
+		Type_:      "int64",
+	}
+	inputUpMessage := buildUpMessageWithRecords(map[string][]flow.Record{
+		"index-hc": {{Value: 100.4, EventTime: eventTime}, {Value: 110.0, EventTime: eventTime.Add(time.Hour)}},
+		"index-hp": {{Value: 20.0, EventTime: eventTime.Add(time.Hour)}, {Value: 10.0, EventTime: eventTime}, {Value: 30.0, EventTime: eventTime.Add(2 * time.Hour)}},
+	})
+	// When
+	outputUpMessage, err := computePointOperation.ApplyUpOperation(&inputUpMessage, &computeOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, flow.INT64__Type, outputUpMessage.Points["totalEnergy"].Type_)
+	assert.Equal(t, []flow.Record{
+		{Value: int64(110), EventTime: eventTime},
+		{Value: int64(130), EventTime: eventTime.Add(time.Hour)},
+	}, outputUpMessage.Points["totalEnergy"].Records)
+}
+
+func Test_should_not_compute_point_when_a_point_is_missing(t *testing.T) {
+	// Given
+	var computeOpr ontology.UpOperationInterface = ontology.UpComputePoint{Point: "dewPoint", Expression: "temperature - (100 - humidity) / 5"}
+	inputUpMessage := buildUpMessageWithPoint("temperature", flow.DOUBLE_Type, 20.0)
+	// When
+	outputUpMessage, err := computePointOperation.ApplyUpOperation(&inputUpMessage, &computeOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, &inputUpMessage, outputUpMessage)
+}
+
+func Test_should_return_error_when_computed_value_is_not_finite(t *testing.T) {
+	// Given
+	var computeOpr ontology.UpOperationInterface = ontology.UpComputePoint{Point: "ratio", Expression: "temperature / (temperature - 20)"}
+	inputUpMessage := buildUpMessageWithPoint("temperature", flow.DOUBLE_Type, 20.0)
+	// When
+	_, err := computePointOperation.ApplyUpOperation(&inputUpMessage, &computeOpr)
+	// Then
+	var mappingError *util.MappingError
+	assert.True(t, errors.As(err, &mappingError))
+	assert.Equal(t, "ratio", mappingError.Key)
+	assert.Equal(t, "the result +Inf is not a finite number", mappingError.Message)
+}
+
+func Test_should_report_invalid_compute_expression_on_validation(t *testing.T) {
+	// Given
+	var computeOpr ontology.UpOperationInterface = ontology.UpComputePoint{Point: "dewPoint", Expression: "temperature - (100 - humidity"}
+	var unknownFunctionOpr ontology.UpOperationInterface = ontology.UpComputePoint{Point: "root", Expression: "cbrt(volume)"}
+	operations := OperationsUpSerDer{Operations: []ontology.UpOperationInterface{computeOpr, unknownFunctionOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	assert.True(t, errors.Is(err, util.ErrInvalidExpression))
+	assert.EqualError(t, err, "2 problem(s) found in mapping: "+
+		"operation 0 'computePoint', key 'dewPoint', expression 'temperature - (100 - humidity': at 29: missing ')'; "+
+		"operation 1 'computePoint', key 'root', expression 'cbrt(volume)': at 5: unknown function 'cbrt'")
+}
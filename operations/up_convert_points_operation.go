@@ -0,0 +1,53 @@
+package operations
+
+import (
+	"ontology-mapping-go-lib/models/flow"
+	"sort"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type UpConvertPointsOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("convertPoints", func() ontology.UpOperationInterface { return &ontology.UpConvertPoints{} }, func() OperationHandler { return &UpConvertPointsOperation{} })
+}
+
+func (convertPoints *UpConvertPointsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	var keys = (*upOperation).(ontology.UpConvertPoints).Points
+	if len(keys) == 0 {
+		for key := range retMessage.Points {
+			keys = append(keys, key)
+		}
+	}
+	if err := convertPointValues(retMessage.Points, keys); err != nil {
+		return nil, err
+	}
+	return retMessage, nil
+}
+
+func (convertPoints *UpConvertPointsOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+// convertPointValues coerces in place the values of the points named by keys to
+// their type, keys missing from points are ignored. Points are converted in key
+// order so that the same message always fails on the same point.
+func convertPointValues(points map[string]flow.Point, keys []string) error {
+	var sortedKeys = append([]string(nil), keys...)
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		point, ok := points[key]
+		if !ok {
+			continue
+		}
+		converted, err := util.ConvertPoint(key, point)
+		if err != nil {
+			return err
+		}
+		points[key] = converted
+	}
+	return nil
+}
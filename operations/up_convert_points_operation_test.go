@@ -0,0 +1,100 @@
+package operations
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+	"time"
+)
+
+var convertPointsOperation = UpConvertPointsOperation{}
+
+func buildUpMessageWithPoint(key string, pointType flow.PointType, values ...interface{}) flow.UpMessage {
+	var eventTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	var records []flow.Record
+	for _, value := range values {
+		records = append(records, flow.Record{Value: value, EventTime: eventTime})
+	}
+	return flow.UpMessage{Time: eventTime, Type_: "deviceUplink", Points: map[string]flow.Point{
+		key: {Type_: pointType, Records: records},
+	}}
+}
+
+func recordValues(point flow.Point) []interface{} {
+	var values []interface{}
+	for _, record := range point.Records {
+		values = append(values, record.Value)
+	}
+	return values
+}
+
+func Test_should_convert_point_values_to_their_type(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertPoints{}
+	inputUpMessage := buildUpMessageWithPoint("counter", flow.INT64__Type, "23", 23.9, -4.2, true)
+	inputUpMessage.Points["open"] = buildUpMessageWithPoint("open", flow.BOOLEAN_Type, 1.0, "false", 0.0).Points["open"]
+	inputUpMessage.Points["temperature"] = buildUpMessageWithPoint("temperature", flow.DOUBLE_Type, "22.5", int64(3)).Points["temperature"]
+	inputUpMessage.Points["label"] = buildUpMessageWithPoint("label", flow.STRING__Type, 12.5, false).Points["label"]
+	// When
+	outputUpMessage, err := convertPointsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(23), int64(23), int64(-4), int64(1)}, recordValues(outputUpMessage.Points["counter"]))
+	assert.Equal(t, []interface{}{true, false, false}, recordValues(outputUpMessage.Points["open"]))
+	assert.Equal(t, []interface{}{22.5, 3.0}, recordValues(outputUpMessage.Points["temperature"]))
+	assert.Equal(t, []interface{}{"12.5", "false"}, recordValues(outputUpMessage.Points["label"]))
+	assert.Equal(t, "23", inputUpMessage.Points["counter"].Records[0].Value)
+}
+
+func Test_should_only_convert_listed_points(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertPoints{Points: []string{"temperature"}}
+	inputUpMessage := buildUpMessageWithPoint("temperature", flow.DOUBLE_Type, "22.5")
+	inputUpMessage.Points["counter"] = buildUpMessageWithPoint("counter", flow.INT64__Type, "23").Points["counter"]
+	// When
+	outputUpMessage, err := convertPointsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, 22.5, outputUpMessage.Points["temperature"].Records[0].Value)
+	assert.Equal(t, "23", outputUpMessage.Points["counter"].Records[0].Value)
+}
+
+func Test_should_return_error_when_int64_is_out_of_range(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertPoints{}
+	inputUpMessage := buildUpMessageWithPoint("counter", flow.INT64__Type, 12.0, 1e19)
+	// When
+	_, err := convertPointsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	assert.True(t, errors.Is(err, util.ErrConversionFailed))
+	assert.EqualError(t, err, "cannot convert value 1e+19 of record 1 to int64: 1e+19 is out of the int64 range")
+}
+
+func Test_should_return_error_when_value_is_not_a_boolean(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertPoints{}
+	inputUpMessage := buildUpMessageWithPoint("open", flow.BOOLEAN_Type, 2.0)
+	// When
+	_, err := convertPointsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	var mappingError *util.MappingError
+	assert.True(t, errors.As(err, &mappingError))
+	assert.Equal(t, "open", mappingError.Key)
+	assert.Equal(t, "cannot convert value 2 of record 0 to boolean: 2 is neither 1 nor 0", mappingError.Message)
+}
+
+func Test_should_convert_values_when_extract_points_flag_is_set(t *testing.T) {
+	// Given
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{ConvertValues: true, Points: map[string]ontology.JmesPathPoint{
+		"temperature": {Value: "{{packet.message.temperature}}", EventTime: "{{time}}", Type_: "int64"},
+	}}
+	// When
+	outputUpMessage, err := jmesPathOperation.ApplyUpOperation(&inputUpMessage, &extractOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, int64(22), outputUpMessage.Points["temperature"].Records[0].Value)
+}
@@ -0,0 +1,92 @@
+package operations
+
+import (
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"sort"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type UpConvertUnitsOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("convertUnits", func() ontology.UpOperationInterface { return &ontology.UpConvertUnits{} }, func() OperationHandler { return &UpConvertUnitsOperation{} })
+}
+
+func (convertUnits *UpConvertUnitsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	var targetUnits = (*upOperation).(ontology.UpConvertUnits).Points
+	var keys []string
+	for key := range targetUnits {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		point, ok := retMessage.Points[key]
+		if !ok {
+			continue
+		}
+		converted, err := convertPointUnit(point, targetUnits[key])
+		if err != nil {
+			return nil, util.WithKey(err, key)
+		}
+		retMessage.Points[key] = converted
+	}
+	return retMessage, nil
+}
+
+func (convertUnits *UpConvertUnitsOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (convertUnits *UpConvertUnitsOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var problems []*util.MappingError
+	for key, unitId := range upOperation.(ontology.UpConvertUnits).Points {
+		if !util.IsKnownUnit(unitId) {
+			problems = append(problems, &util.MappingError{Code: util.UNKNOWN_UNIT_ErrorCode, Key: key, Message: fmt.Sprintf("unknown unit '%s'", unitId)})
+		}
+	}
+	return problems
+}
+
+func (convertUnits *UpConvertUnitsOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+// convertPointUnit rescales the values of point to unitId. The values become
+// doubles, so an int64 point becomes a double point.
+func convertPointUnit(point flow.Point, unitId string) (flow.Point, error) {
+	if point.UnitId == unitId {
+		return point, nil
+	}
+	if len(point.UnitId) == 0 {
+		return point, util.NewMappingError(util.UNKNOWN_UNIT_ErrorCode, fmt.Sprintf("the point has no unitId to convert to '%s'", unitId))
+	}
+	// checked before the records, a point without values gets no wrong unitId either
+	if err := util.ValidateUnitConversion(point.UnitId, unitId); err != nil {
+		return point, err
+	}
+	var records = make([]flow.Record, len(point.Records))
+	for i, record := range point.Records {
+		records[i] = record
+		if record.Value == nil {
+			continue
+		}
+		value, err := util.ConvertValue(record.Value, flow.DOUBLE_Type)
+		if err != nil {
+			return point, &util.MappingError{Code: util.CONVERSION_FAILED_ErrorCode,
+				Message: fmt.Sprintf("cannot convert value %v of record %d to '%s': %v", record.Value, i, unitId, err), Err: err}
+		}
+		if records[i].Value, err = util.ConvertUnit(value.(float64), point.UnitId, unitId); err != nil {
+			return point, err
+		}
+	}
+	if point.Type_ == flow.INT64__Type {
+		point.Type_ = flow.DOUBLE_Type
+	}
+	point.UnitId = unitId
+	point.Records = records
+	return point, nil
+}
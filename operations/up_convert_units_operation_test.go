@@ -0,0 +1,104 @@
+package operations
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+)
+
+var convertUnitsOperation = UpConvertUnitsOperation{}
+
+func buildUpMessageWithUnit(key string, unitId string, pointType flow.PointType, values ...interface{}) flow.UpMessage {
+	var message = buildUpMessageWithPoint(key, pointType, values...)
+	var point = message.Points[key]
+	point.UnitId = unitId
+	message.Points[key] = point
+	return message
+}
+
+func Test_should_convert_point_units(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertUnits{Points: map[string]string{
+		"temperature": "degF", "pressure": "hPa", "energy": "kWh", "missing": "Cel",
+	}}
+	inputUpMessage := buildUpMessageWithUnit("temperature", "Cel", flow.DOUBLE_Type, 22.0, -40.0)
+	inputUpMessage.Points["pressure"] = buildUpMessageWithUnit("pressure", "Pa", flow.INT64__Type, int64(101325)).Points["pressure"]
+	inputUpMessage.Points["energy"] = buildUpMessageWithUnit("energy", "Wh", flow.DOUBLE_Type, "1500").Points["energy"]
+	// When
+	outputUpMessage, err := convertUnitsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	assert.Nil(t, err)
+	var temperature = outputUpMessage.Points["temperature"]
+	assert.Equal(t, "degF", temperature.UnitId)
+	assert.InDelta(t, 71.6, temperature.Records[0].Value, 1e-9)
+	assert.InDelta(t, -40.0, temperature.Records[1].Value, 1e-9)
+	var pressure = outputUpMessage.Points["pressure"]
+	assert.Equal(t, "hPa", pressure.UnitId)
+	assert.Equal(t, flow.DOUBLE_Type, pressure.Type_)
+	assert.InDelta(t, 1013.25, pressure.Records[0].Value, 1e-9)
+	assert.InDelta(t, 1.5, outputUpMessage.Points["energy"].Records[0].Value, 1e-9)
+	assert.Equal(t, "Cel", inputUpMessage.Points["temperature"].UnitId)
+	assert.NotContains(t, outputUpMessage.Points, "missing")
+}
+
+func Test_should_return_error_when_units_are_incompatible(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertUnits{Points: map[string]string{"temperature": "hPa"}}
+	inputUpMessage := buildUpMessageWithUnit("temperature", "Cel", flow.DOUBLE_Type, 22.0)
+	// When
+	_, err := convertUnitsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	assert.True(t, errors.Is(err, util.ErrIncompatibleUnits))
+	assert.EqualError(t, err, "cannot convert 'Cel' (temperature) to 'hPa' (pressure)")
+}
+
+func Test_should_return_error_when_units_are_incompatible_without_values(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertUnits{Points: map[string]string{"temperature": "hPa", "pressure": "Cel"}}
+	inputUpMessage := buildUpMessageWithUnit("temperature", "Cel", flow.DOUBLE_Type, nil)
+	inputUpMessage.Points["pressure"] = flow.Point{Type_: flow.DOUBLE_Type, UnitId: "Pa"}
+	// When
+	_, err := convertUnitsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	var mappingErr *util.MappingError
+	assert.True(t, errors.As(err, &mappingErr))
+	assert.True(t, errors.Is(err, util.ErrIncompatibleUnits))
+	assert.Equal(t, "pressure", mappingErr.Key)
+}
+
+func Test_should_return_error_when_point_unit_is_unknown(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertUnits{Points: map[string]string{"temperature": "Cel"}}
+	inputUpMessage := buildUpMessageWithUnit("temperature", "degX", flow.DOUBLE_Type)
+	// When
+	_, err := convertUnitsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	assert.True(t, errors.Is(err, util.ErrUnknownUnit))
+	assert.EqualError(t, err, "unknown unit 'degX'")
+}
+
+func Test_should_report_unknown_target_unit_on_validation(t *testing.T) {
+	// Given
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertUnits{Points: map[string]string{"temperature": "degX"}}
+	operations := OperationsUpSerDer{Operations: []ontology.UpOperationInterface{convertOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	assert.True(t, errors.Is(err, util.ErrUnknownUnit))
+	assert.EqualError(t, err, "1 problem(s) found in mapping: operation 0 'convertUnits', key 'temperature': unknown unit 'degX'")
+}
+
+func Test_should_convert_to_registered_unit(t *testing.T) {
+	// Given
+	_ = util.RegisterUnit("inHg", "pressure", 3386.389, 0)
+	var convertOpr ontology.UpOperationInterface = ontology.UpConvertUnits{Points: map[string]string{"pressure": "inHg"}}
+	inputUpMessage := buildUpMessageWithUnit("pressure", "hPa", flow.DOUBLE_Type, 1013.25)
+	// When
+	outputUpMessage, err := convertUnitsOperation.ApplyUpOperation(&inputUpMessage, &convertOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.InDelta(t, 29.92, outputUpMessage.Points["pressure"].Records[0].Value, 0.01)
+}
@@ -1,8 +1,10 @@
 package operations
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"ontology-mapping-go-lib/models/flow"
+	"time"
 )
 import "ontology-mapping-go-lib/models/ontology"
 import "ontology-mapping-go-lib/util"
@@ -10,29 +12,46 @@ import "ontology-mapping-go-lib/util"
 type UpExtractPointsOperation struct {
 }
 
+func init() {
+	MustRegisterUpOperation("extractPoints", func() ontology.UpOperationInterface { return &ontology.UpExtractPoints{} }, func() OperationHandler { return &UpExtractPointsOperation{} })
+}
+
 func (extractPoints *UpExtractPointsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return extractPoints.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
+
+func (extractPoints *UpExtractPointsOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
 	var newPoints = make(map[string]flow.Point)
 	var retMessage = util.CopyUpMessage(message)
 	if len(retMessage.Points) > 0 {
 		newPoints = retMessage.Points
 	}
 	var jmesPathOperation = (*upOperation).(ontology.UpExtractPoints)
-	var messageJson interface{} = message
-	var err error
+	messageJson, err := jsonView(ctx, message)
+	if err != nil {
+		return nil, err
+	}
 	for key, element := range jmesPathOperation.Points {
 		var isValue = false
 		var values interface{}
 		if len(element.Value) > 0 {
-			values, err = util.RetrieveValues(element.Value, &messageJson)
+			values, err = util.RetrieveValuesContext(ctx, element.Value, &messageJson)
 			if err != nil {
-				return nil, err
+				return nil, util.WithKey(err, key)
 			}
 			isValue = true
 		}
 		var eventTime interface{}
-		eventTime, err = util.RetrieveValues(element.EventTime, &messageJson)
+		eventTime, err = util.RetrieveValuesContext(ctx, element.EventTime, &messageJson)
 		if err != nil {
-			return nil, err
+			return nil, util.WithKey(err, key)
+		}
+		var samplePeriod time.Duration
+		if len(element.SamplePeriod) > 0 {
+			samplePeriod, err = retrieveSamplePeriod(ctx, element.SamplePeriod, &messageJson)
+			if err != nil {
+				return nil, util.WithKey(err, key)
+			}
 		}
 		var longitude interface{}
 		var latitude interface{}
@@ -41,30 +60,36 @@ func (extractPoints *UpExtractPointsOperation) ApplyUpOperation(message *flow.Up
 		var isCoordinate = false
 		if element.Coordinates != nil {
 			isCoordinate = true
+			var longitudeIndex, latitudeIndex = 0, 1
+			if element.CoordinateOrder == ontology.LAT_LNG_CoordinateOrder {
+				longitudeIndex, latitudeIndex = 1, 0
+			}
 			if len(element.Coordinates) == 2 {
-				longitude, err = util.RetrieveValues(element.Coordinates[0], &messageJson)
-				latitude, err = util.RetrieveValues(element.Coordinates[1], &messageJson)
+				longitude, err = util.RetrieveValuesContext(ctx, element.Coordinates[longitudeIndex], &messageJson)
+				latitude, err = util.RetrieveValuesContext(ctx, element.Coordinates[latitudeIndex], &messageJson)
 				if err != nil {
-					return nil, err
+					return nil, util.WithKey(err, key)
 				}
 			} else if len(element.Coordinates) == 3 {
-				longitude, err = util.RetrieveValues(element.Coordinates[0], &messageJson)
-				latitude, err = util.RetrieveValues(element.Coordinates[1], &messageJson)
-				altitude, err = util.RetrieveValues(element.Coordinates[2], &messageJson)
+				longitude, err = util.RetrieveValuesContext(ctx, element.Coordinates[longitudeIndex], &messageJson)
+				latitude, err = util.RetrieveValuesContext(ctx, element.Coordinates[latitudeIndex], &messageJson)
+				altitude, err = util.RetrieveValuesContext(ctx, element.Coordinates[2], &messageJson)
 				isAltitude = true
 				if err != nil {
-					return nil, err
+					return nil, util.WithKey(err, key)
 				}
 			} else {
-				err = errors.New("invalid 'coordinate' length, it must be 2 or 3 %s" + key)
-				return nil, err
+				err = &util.MappingError{Code: util.INVALID_COORDINATES_ErrorCode, Key: key, Message: "invalid 'coordinate' length, it must be 2 or 3"}
+				return nil, util.WithKey(err, key)
 			}
 		}
-		var params = util.PointParams{Values: values, EventTime: eventTime, Longitude: longitude, Latitude: latitude, Altitude: altitude, IsAltitude: isAltitude, IsValue: isValue, IsCoordinate: isCoordinate}
+		var params = util.PointParams{Values: values, EventTime: eventTime, Longitude: longitude, Latitude: latitude, Altitude: altitude, IsAltitude: isAltitude, IsValue: isValue, IsCoordinate: isCoordinate,
+			CoordinateFormat: element.CoordinateFormat, CoordinateScale: element.CoordinateScale, EventTimeFormat: element.EventTimeFormat, Timezone: element.Timezone,
+			SamplePeriod: samplePeriod, SampleDirection: element.SampleDirection}
 		var records []flow.Record
-		records, err = util.ExtractRecords(params, key)
+		records, err = util.ExtractRecordsContext(ctx, params, key)
 		if err != nil {
-			return nil, err
+			return nil, util.WithKey(err, key)
 		}
 		var assignpointType flow.PointType
 		if &element.Type_ != nil {
@@ -75,6 +100,15 @@ func (extractPoints *UpExtractPointsOperation) ApplyUpOperation(message *flow.Up
 			newPoints[key] = flow.Point{OntologyId: element.OntologyId, UnitId: element.UnitId, Records: records, Type_: assignpointType}
 		}
 	}
+	if jmesPathOperation.ConvertValues {
+		var keys []string
+		for key := range jmesPathOperation.Points {
+			keys = append(keys, key)
+		}
+		if err = convertPointValues(newPoints, keys); err != nil {
+			return nil, err
+		}
+	}
 	if len(newPoints) > 0 {
 		retMessage.Points = newPoints
 	}
@@ -84,3 +118,60 @@ func (extractPoints *UpExtractPointsOperation) ApplyUpOperation(message *flow.Up
 func (extractPoints *UpExtractPointsOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
 	return nil, nil
 }
+
+func (extractPoints *UpExtractPointsOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (extractPoints *UpExtractPointsOperation) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	var expressions []string
+	for _, point := range upOperation.(ontology.UpExtractPoints).Points {
+		expressions = append(expressions, point.Value, point.EventTime, point.SamplePeriod)
+		expressions = append(expressions, point.Coordinates...)
+	}
+	return expressions
+}
+
+func (extractPoints *UpExtractPointsOperation) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	return nil
+}
+
+func (extractPoints *UpExtractPointsOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var jmesPathOperation = upOperation.(ontology.UpExtractPoints)
+	if len(jmesPathOperation.Points) == 0 {
+		return []*util.MappingError{util.NewMappingError(util.EMPTY_POINTS_ErrorCode, "'points' must not be empty")}
+	}
+	var problems []*util.MappingError
+	for key, element := range jmesPathOperation.Points {
+		problems = append(problems, validatePoint(key, element.Value, element.EventTime, element.Coordinates, element.Type_)...)
+		problems = append(problems, validateCoordinateFormat(key, element)...)
+		problems = append(problems, validateEventTimeFormat(key, element.EventTimeFormat, element.Timezone)...)
+		problems = append(problems, validateSampling(key, element)...)
+	}
+	return problems
+}
+
+func (extractPoints *UpExtractPointsOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+// retrieveSamplePeriod reads samplePeriod, a duration or a number of seconds given
+// as is or by an expression.
+func retrieveSamplePeriod(ctx context.Context, samplePeriod string, message *interface{}) (time.Duration, error) {
+	if !util.IsJmesExpression(samplePeriod) {
+		period, err := util.ParseSamplePeriod(samplePeriod)
+		if err != nil {
+			return 0, &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Message: fmt.Sprintf("invalid 'samplePeriod': %v", err), Err: err}
+		}
+		return period, nil
+	}
+	value, err := util.RetrieveValuesContext(ctx, samplePeriod, message)
+	if err != nil {
+		return 0, err
+	}
+	period, err := util.ParseSamplePeriod(value)
+	if err != nil {
+		return 0, &util.MappingError{Code: util.UNEXPECTED_RESULT_ErrorCode, Expression: samplePeriod, Message: fmt.Sprintf("invalid 'samplePeriod': %v", err), Err: err}
+	}
+	return period, nil
+}
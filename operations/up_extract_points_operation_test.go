@@ -2,6 +2,7 @@ package operations
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"ontology-mapping-go-lib/util"
 	"testing"
@@ -508,6 +509,22 @@ func Test_should_include_point_when_event_time_is_array_of_equal_length_1_and_va
 	assert.Equal(t, outputUpMessage, expectedOutputMessage)
 }
 
+func Test_should_return_error_when_coordinates_have_one_member(t *testing.T) {
+	// Given
+	inputUpMessage := buildInputUpMessage("coordinates_not_array_event_time_not_array.json")
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"coordinates": {Coordinates: []string{"{{packet.message}}"}, EventTime: "{{time}}"},
+	}}
+	// When
+	_, err := jmesPathOperation.ApplyUpOperation(&inputUpMessage, &extractOpr)
+	// Then
+	var mappingErr *util.MappingError
+	assert.True(t, errors.As(err, &mappingErr))
+	assert.True(t, errors.Is(err, util.ErrInvalidCoordinates))
+	assert.Equal(t, "coordinates", mappingErr.Key)
+	assert.Equal(t, "invalid 'coordinate' length, it must be 2 or 3", mappingErr.Message)
+}
+
 func Test_should_include_point_when_event_time_is_not_array_and_coordinate_is_not_an_array(t *testing.T) {
 	// Given
 
@@ -1778,3 +1795,280 @@ func Test_should_include_ontology_id_existing_points(t *testing.T) {
 	expectedOutputMessage.Points = expectedPoints
 	assert.Equal(t, outputUpMessage, expectedOutputMessage)
 }
+
+func extractCoordinates(t *testing.T, message interface{}, location ontology.JmesPathPoint) ([]float64, error) {
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	inputUpMessage.Packet.Message = message
+	location.EventTime = "{{time}}"
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{"location": location}}
+	outputUpMessage, err := jmesPathOperation.ApplyUpOperation(&inputUpMessage, &extractOpr)
+	if err != nil {
+		return nil, err
+	}
+	assert.Equal(t, 1, len(outputUpMessage.Points["location"].Records))
+	return outputUpMessage.Points["location"].Records[0].Coordinates, nil
+}
+
+func Test_should_extract_degrees_minutes_seconds_coordinates_in_lat_lng_order(t *testing.T) {
+	// Given
+	var message = map[string]interface{}{"lat": "43°39'42.75\"N", "lng": "7 3 31.32 W"}
+	var location = ontology.JmesPathPoint{Coordinates: []string{"{{packet.message.lat}}", "{{packet.message.lng}}"},
+		CoordinateOrder: ontology.LAT_LNG_CoordinateOrder, CoordinateFormat: ontology.DMS_CoordinateFormat}
+	// When
+	coordinates, err := extractCoordinates(t, message, location)
+	// Then
+	assert.Nil(t, err)
+	assert.InDelta(t, -7.0587, coordinates[0], 1e-9)
+	assert.InDelta(t, 43.661875, coordinates[1], 1e-9)
+}
+
+func Test_should_extract_nmea_coordinates(t *testing.T) {
+	// Given
+	var message = map[string]interface{}{"lat": "4339.7125,S", "lng": 703.522, "alt": 120.5}
+	var location = ontology.JmesPathPoint{Coordinates: []string{"{{packet.message.lng}}", "{{packet.message.lat}}", "{{packet.message.alt}}"},
+		CoordinateFormat: ontology.NMEA_CoordinateFormat}
+	// When
+	coordinates, err := extractCoordinates(t, message, location)
+	// Then
+	assert.Nil(t, err)
+	assert.InDelta(t, 7.0587, coordinates[0], 1e-9)
+	assert.InDelta(t, -43.661875, coordinates[1], 1e-9)
+	assert.Equal(t, 120.5, coordinates[2])
+}
+
+func Test_should_extract_scaled_coordinates(t *testing.T) {
+	// Given
+	var message = map[string]interface{}{"lat": 43661875.0, "lng": "7058700"}
+	var microdegrees = ontology.JmesPathPoint{Coordinates: []string{"{{packet.message.lng}}", "{{packet.message.lat}}"},
+		CoordinateFormat: ontology.SCALED_CoordinateFormat}
+	var decimicrodegrees = microdegrees
+	decimicrodegrees.CoordinateScale = 10000000
+	// When
+	coordinates, err := extractCoordinates(t, message, microdegrees)
+	scaledCoordinates, scaledErr := extractCoordinates(t, message, decimicrodegrees)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, scaledErr)
+	assert.InDelta(t, 7.0587, coordinates[0], 1e-9)
+	assert.InDelta(t, 43.661875, coordinates[1], 1e-9)
+	assert.InDelta(t, 4.3661875, scaledCoordinates[1], 1e-9)
+}
+
+func Test_should_reject_invalid_coordinates(t *testing.T) {
+	// Given
+	var coordinates = []string{"{{packet.message.lng}}", "{{packet.message.lat}}"}
+	var invalidLocations = []struct {
+		format          ontology.CoordinateFormat
+		message         map[string]interface{}
+		expectedMessage string
+	}{
+		{ontology.DECIMAL_CoordinateFormat, map[string]interface{}{"lat": 93.5, "lng": 7.05}, "latitude 93.5 is out of range [-90, 90]"},
+		{ontology.DECIMAL_CoordinateFormat, map[string]interface{}{"lat": 43.6, "lng": "-180.5"}, "longitude -180.5 is out of range [-180, 180]"},
+		{ontology.DMS_CoordinateFormat, map[string]interface{}{"lat": "43°39'42\"E", "lng": "7°3'31\"E"}, "invalid dms latitude 43°39'42\"E: hemisphere 'E' is not a latitude hemisphere"},
+		{ontology.DMS_CoordinateFormat, map[string]interface{}{"lat": "43°39'42\"N", "lng": "7°63'31\"E"}, "invalid dms longitude 7°63'31\"E: minutes and seconds must be less than 60"},
+		{ontology.NMEA_CoordinateFormat, map[string]interface{}{"lat": "4339.7125,N", "lng": "north"}, "invalid nmea longitude north: expected [d]ddmm.mmmm"},
+	}
+	for _, location := range invalidLocations {
+		// When
+		_, err := extractCoordinates(t, location.message, ontology.JmesPathPoint{Coordinates: coordinates, CoordinateFormat: location.format})
+		// Then
+		assert.True(t, errors.Is(err, util.ErrInvalidCoordinates))
+		assert.Equal(t, "location", err.(*util.MappingError).Key)
+		assert.Equal(t, location.expectedMessage, err.(*util.MappingError).Message)
+	}
+}
+
+func Test_should_report_unknown_coordinate_format_and_order(t *testing.T) {
+	// Given
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{"location": {
+		Coordinates: []string{"{{packet.message.lng}}", "{{packet.message.lat}}"}, EventTime: "{{time}}",
+		CoordinateFormat: "utm", CoordinateOrder: "xy"}}}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{extractOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, len(validationErr.Problems))
+	assert.Equal(t, "operation 0 'extractPoints', key 'location': unknown 'coordinateFormat' 'utm'", validationErr.Problems[0].Error())
+	assert.Equal(t, "operation 0 'extractPoints', key 'location': unknown 'coordinateOrder' 'xy', expected 'lngLat' or 'latLng'", validationErr.Problems[1].Error())
+}
+
+func extractEventTime(t *testing.T, message interface{}, temperature ontology.JmesPathPoint) (time.Time, error) {
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	inputUpMessage.Packet.Message = message
+	temperature.Value = "{{packet.message.temperature}}"
+	temperature.EventTime = "{{packet.message.time}}"
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{"temperature": temperature}}
+	outputUpMessage, err := jmesPathOperation.ApplyUpOperation(&inputUpMessage, &extractOpr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	assert.Equal(t, 1, len(outputUpMessage.Points["temperature"].Records))
+	return outputUpMessage.Points["temperature"].Records[0].EventTime, nil
+}
+
+func Test_should_extract_event_time_in_configured_format(t *testing.T) {
+	// Given
+	var expectedTime, _ = time.Parse(time.RFC3339, "2021-03-09T10:15:00Z")
+	var eventTimes = []struct {
+		format   ontology.EventTimeFormat
+		timezone string
+		time     interface{}
+	}{
+		{"", "", "2021-03-09T10:15:00Z"},
+		{ontology.RFC3339_EventTimeFormat, "", "2021-03-09T11:15:00+01:00"},
+		{ontology.EPOCH_S_EventTimeFormat, "", 1615284900.0},
+		{ontology.EPOCH_S_EventTimeFormat, "", "1615284900"},
+		{ontology.EPOCH_MS_EventTimeFormat, "", 1615284900000.0},
+		{ontology.GPS_EventTimeFormat, "", 1299320118.0},
+		{"2006-01-02 15:04:05", "", "2021-03-09 10:15:00"},
+		{"2006-01-02 15:04:05", "Europe/Paris", "2021-03-09 11:15:00"},
+		{"20060102150405", "Europe/Paris", 20210309111500.0},
+	}
+	for _, eventTime := range eventTimes {
+		var message = map[string]interface{}{"temperature": 22.6, "time": eventTime.time}
+		// When
+		extractedTime, err := extractEventTime(t, message, ontology.JmesPathPoint{EventTimeFormat: eventTime.format, Timezone: eventTime.timezone})
+		// Then
+		assert.Nil(t, err)
+		assert.True(t, expectedTime.Equal(extractedTime), "%v read as %s is %v", eventTime.time, eventTime.format, extractedTime)
+	}
+}
+
+func Test_should_keep_milliseconds_of_epoch_event_time(t *testing.T) {
+	// Given
+	var message = map[string]interface{}{"temperature": 22.6, "time": 1615284900.125}
+	// When
+	extractedTime, err := extractEventTime(t, message, ontology.JmesPathPoint{EventTimeFormat: ontology.EPOCH_S_EventTimeFormat})
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "2021-03-09T10:15:00.125Z", extractedTime.Format(time.RFC3339Nano))
+}
+
+func Test_should_read_epoch_event_time_beyond_duration_range(t *testing.T) {
+	// Given
+	var message = map[string]interface{}{"temperature": 22.6, "time": 1e10}
+	// When
+	extractedTime, err := extractEventTime(t, message, ontology.JmesPathPoint{EventTimeFormat: ontology.EPOCH_S_EventTimeFormat})
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "2286-11-20T17:46:40Z", extractedTime.Format(time.RFC3339Nano))
+}
+
+func Test_should_reject_event_time_not_in_configured_format(t *testing.T) {
+	// Given
+	var invalidEventTimes = []struct {
+		format          ontology.EventTimeFormat
+		time            interface{}
+		expectedMessage string
+	}{
+		{ontology.EPOCH_MS_EventTimeFormat, "yesterday", "invalid epoch_ms eventTime yesterday: not a number"},
+		{ontology.EPOCH_S_EventTimeFormat, 1e12, "invalid epoch_s eventTime 1e+12: out of range"},
+		{ontology.EPOCH_MS_EventTimeFormat, -1e20, "invalid epoch_ms eventTime -1e+20: out of range"},
+		{ontology.RFC3339_EventTimeFormat, 1615284900.0, "error while converting interface to time.Time"},
+		{"2006-01-02 15:04:05", "09/03/2021 10:15", `parsing time "09/03/2021 10:15" as "2006-01-02 15:04:05": cannot parse "09/03/2021 10:15" as "2006"`},
+	}
+	for _, eventTime := range invalidEventTimes {
+		var message = map[string]interface{}{"temperature": 22.6, "time": eventTime.time}
+		// When
+		_, err := extractEventTime(t, message, ontology.JmesPathPoint{EventTimeFormat: eventTime.format})
+		// Then
+		assert.True(t, errors.Is(err, util.ErrInvalidEventTime))
+		assert.Equal(t, "temperature", err.(*util.MappingError).Key)
+		assert.Equal(t, eventTime.expectedMessage, err.(*util.MappingError).Message)
+	}
+}
+
+func Test_should_report_unknown_event_time_format_and_timezone(t *testing.T) {
+	// Given
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"humidity":    {Value: "{{packet.message.humidity}}", EventTime: "{{packet.message.time}}", EventTimeFormat: "epoch_us"},
+		"temperature": {Value: "{{packet.message.temperature}}", EventTime: "{{packet.message.time}}", EventTimeFormat: "2006-01-02 15:04", Timezone: "Mars/Olympus_Mons"},
+	}}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{extractOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, len(validationErr.Problems))
+	assert.Equal(t, "operation 0 'extractPoints', key 'humidity': unknown 'eventTimeFormat' 'epoch_us', expected rfc3339, epoch_s, epoch_ms, gps or a Go time layout", validationErr.Problems[0].Error())
+	assert.Equal(t, "operation 0 'extractPoints', key 'temperature': unknown timezone 'Mars/Olympus_Mons'", validationErr.Problems[1].Error())
+}
+
+func extractSamples(message interface{}, temperature ontology.JmesPathPoint) ([]time.Time, error) {
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	inputUpMessage.Packet.Message = message
+	temperature.Value = "{{packet.message.temperatures}}"
+	temperature.EventTime = "{{packet.message.time}}"
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{"temperature": temperature}}
+	outputUpMessage, err := jmesPathOperation.ApplyUpOperation(&inputUpMessage, &extractOpr)
+	if err != nil {
+		return nil, err
+	}
+	var eventTimes []time.Time
+	for _, record := range outputUpMessage.Points["temperature"].Records {
+		eventTimes = append(eventTimes, record.EventTime.UTC())
+	}
+	return eventTimes, nil
+}
+
+func Test_should_generate_event_times_from_sample_period(t *testing.T) {
+	// Given
+	var message = map[string]interface{}{"temperatures": []interface{}{11.625, 11.625, 11.6875}, "time": "2020-02-06T09:14:05.688Z", "period": 100.0}
+	var base, _ = time.Parse(time.RFC3339, "2020-02-06T09:14:05.688Z")
+	// When
+	forwardTimes, forwardErr := extractSamples(message, ontology.JmesPathPoint{SamplePeriod: "100s"})
+	backwardTimes, backwardErr := extractSamples(message, ontology.JmesPathPoint{SamplePeriod: "{{packet.message.period}}", SampleDirection: ontology.BACKWARD_SampleDirection})
+	// Then
+	assert.Nil(t, forwardErr)
+	assert.Nil(t, backwardErr)
+	assert.Equal(t, []time.Time{base, base.Add(100 * time.Second), base.Add(200 * time.Second)}, forwardTimes)
+	assert.Equal(t, []time.Time{base, base.Add(-100 * time.Second), base.Add(-200 * time.Second)}, backwardTimes)
+}
+
+func Test_should_read_sample_base_time_in_configured_format(t *testing.T) {
+	// Given
+	var message = map[string]interface{}{"temperatures": []interface{}{22.5, 22.75}, "time": 1615284900.0}
+	var base, _ = time.Parse(time.RFC3339, "2021-03-09T10:15:00Z")
+	// When
+	eventTimes, err := extractSamples(message, ontology.JmesPathPoint{SamplePeriod: "0.5", EventTimeFormat: ontology.EPOCH_S_EventTimeFormat})
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{base, base.Add(500 * time.Millisecond)}, eventTimes)
+}
+
+func Test_should_reject_sample_period_without_single_base_time(t *testing.T) {
+	// Given
+	var message = map[string]interface{}{"temperatures": []interface{}{22.5, 22.75},
+		"time": []interface{}{"2021-03-09T10:15:00Z", "2021-03-09T10:25:00Z"}, "period": "soon"}
+	// When
+	_, cardinalityErr := extractSamples(message, ontology.JmesPathPoint{SamplePeriod: "10m"})
+	message["time"] = "2021-03-09T10:15:00Z"
+	_, periodErr := extractSamples(message, ontology.JmesPathPoint{SamplePeriod: "{{packet.message.period}}"})
+	// Then
+	assert.True(t, errors.Is(cardinalityErr, util.ErrCardinalityMismatch))
+	assert.Equal(t, "temperature", cardinalityErr.(*util.MappingError).Key)
+	assert.Equal(t, "'eventTime' must give a single base time when 'samplePeriod' is set, got 2", cardinalityErr.(*util.MappingError).Message)
+	assert.True(t, errors.Is(periodErr, util.ErrUnexpectedResult))
+	assert.Equal(t, "invalid 'samplePeriod': 'soon' is neither a duration nor a number of seconds", periodErr.(*util.MappingError).Message)
+}
+
+func Test_should_report_invalid_sample_period_and_direction(t *testing.T) {
+	// Given
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"humidity":    {Value: "{{packet.message.humidity}}", EventTime: "{{packet.message.time}}", SamplePeriod: "-10m", SampleDirection: "sideways"},
+		"temperature": {Value: "{{packet.message.temperature}}", EventTime: "{{packet.message.time}}", SampleDirection: ontology.BACKWARD_SampleDirection},
+	}}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{extractOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 3, len(validationErr.Problems))
+	assert.Equal(t, "operation 0 'extractPoints', key 'humidity': invalid 'samplePeriod': -10m is not positive", validationErr.Problems[0].Error())
+	assert.Equal(t, "operation 0 'extractPoints', key 'humidity': unknown 'sampleDirection' 'sideways', expected 'forward' or 'backward'", validationErr.Problems[1].Error())
+	assert.Equal(t, "operation 0 'extractPoints', key 'temperature': 'sampleDirection' needs a 'samplePeriod'", validationErr.Problems[2].Error())
+}
@@ -0,0 +1,161 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"time"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type UpFilterRecordsOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("filterRecords", func() ontology.UpOperationInterface { return &ontology.UpFilterRecords{} }, func() OperationHandler { return &UpFilterRecordsOperation{} })
+}
+
+func (filterRecords *UpFilterRecordsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return filterRecords.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
+
+// ApplyUpOperationContext drops the records failing a predicate, and the points
+// left without records. Min and Max drop the records without a numeric value.
+func (filterRecords *UpFilterRecordsOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	var filterOperation = (*upOperation).(ontology.UpFilterRecords)
+	from, to, err := eventTimeWindow(message.Time, filterOperation.EventTime)
+	if err != nil {
+		return nil, err
+	}
+	for key, point := range retMessage.Points {
+		if len(filterOperation.Points) > 0 && !util.Contains(filterOperation.Points, key) {
+			continue
+		}
+		var records []flow.Record
+		for _, record := range point.Records {
+			keep, err := keepRecord(ctx, key, record, filterOperation, from, to)
+			if err != nil {
+				return nil, util.WithKey(err, key)
+			}
+			if keep {
+				records = append(records, record)
+			}
+		}
+		if len(records) == 0 {
+			delete(retMessage.Points, key)
+			continue
+		}
+		point.Records = records
+		retMessage.Points[key] = point
+	}
+	return retMessage, nil
+}
+
+func (filterRecords *UpFilterRecordsOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (filterRecords *UpFilterRecordsOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (filterRecords *UpFilterRecordsOperation) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	return []string{upOperation.(ontology.UpFilterRecords).Expression}
+}
+
+func (filterRecords *UpFilterRecordsOperation) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	return nil
+}
+
+func (filterRecords *UpFilterRecordsOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var filterOperation = upOperation.(ontology.UpFilterRecords)
+	var problems = validateExpressions("", []string{filterOperation.Expression})
+	if filterOperation.Min != nil && filterOperation.Max != nil && *filterOperation.Min > *filterOperation.Max {
+		problems = append(problems, util.NewMappingError(util.INVALID_RULE_ErrorCode, fmt.Sprintf("'min' %v is greater than 'max' %v", *filterOperation.Min, *filterOperation.Max)))
+	}
+	if _, _, err := eventTimeWindow(time.Time{}, filterOperation.EventTime); err != nil {
+		problems = append(problems, err.(*util.MappingError))
+	}
+	return problems
+}
+
+func (filterRecords *UpFilterRecordsOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+// eventTimeWindow returns the bounds of window around messageTime, a zero time
+// for an open bound.
+func eventTimeWindow(messageTime time.Time, window *ontology.EventTimeWindow) (time.Time, time.Time, error) {
+	var bounds [2]time.Time
+	if window == nil {
+		return bounds[0], bounds[1], nil
+	}
+	for i, bound := range []string{window.From, window.To} {
+		if len(bound) == 0 {
+			continue
+		}
+		offset, err := time.ParseDuration(bound)
+		if err != nil {
+			return bounds[0], bounds[1], &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Message: fmt.Sprintf("invalid 'eventTime' bound: %v", err), Err: err}
+		}
+		bounds[i] = messageTime.Add(offset)
+	}
+	return bounds[0], bounds[1], nil
+}
+
+func keepRecord(ctx context.Context, key string, record flow.Record, filterOperation ontology.UpFilterRecords, from time.Time, to time.Time) (bool, error) {
+	if filterOperation.HasCoordinates != nil && *filterOperation.HasCoordinates != (len(record.Coordinates) > 0) {
+		return false, nil
+	}
+	if !from.IsZero() && record.EventTime.Before(from) || !to.IsZero() && record.EventTime.After(to) {
+		return false, nil
+	}
+	if filterOperation.Min != nil || filterOperation.Max != nil {
+		if record.Value == nil {
+			return false, nil
+		}
+		value, err := util.ConvertValue(record.Value, flow.DOUBLE_Type)
+		if err != nil {
+			return false, nil
+		}
+		if filterOperation.Min != nil && value.(float64) < *filterOperation.Min || filterOperation.Max != nil && value.(float64) > *filterOperation.Max {
+			return false, nil
+		}
+	}
+	if len(filterOperation.Expression) == 0 {
+		return true, nil
+	}
+	var recordJson interface{} = map[string]interface{}{
+		"point":       key,
+		"value":       record.Value,
+		"eventTime":   record.EventTime.Format(time.RFC3339Nano),
+		"coordinates": coordinatesJson(record.Coordinates),
+	}
+	result, err := util.RetrieveValuesContext(ctx, filterOperation.Expression, &recordJson)
+	if err != nil {
+		return false, err
+	}
+	switch keep := result.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return keep, nil
+	default:
+		return false, &util.MappingError{Code: util.UNEXPECTED_RESULT_ErrorCode, Expression: filterOperation.Expression,
+			Message: fmt.Sprintf("expected a boolean, got %v", result)}
+	}
+}
+
+// coordinatesJson gives the coordinates the shape they have once decoded from JSON.
+func coordinatesJson(coordinates []float64) interface{} {
+	if len(coordinates) == 0 {
+		return nil
+	}
+	var values = make([]interface{}, len(coordinates))
+	for i, coordinate := range coordinates {
+		values[i] = coordinate
+	}
+	return values
+}
@@ -0,0 +1,104 @@
+package operations
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+	"time"
+)
+
+var filterRecordsOperation = UpFilterRecordsOperation{}
+
+func buildUpMessageForRecordFilter() flow.UpMessage {
+	var messageTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	return buildUpMessageWithRecords(map[string][]flow.Record{
+		"temperature": {
+			{Value: -60.0, EventTime: messageTime.Add(-time.Hour)},
+			{Value: 21.5, EventTime: messageTime.Add(-48 * time.Hour)},
+			{Value: 22.0, EventTime: messageTime},
+			{Value: "n/a", EventTime: messageTime},
+		},
+		"location": {
+			{Coordinates: []float64{7.05, 43.62}, EventTime: messageTime},
+			{Coordinates: []float64{0, 0}, EventTime: messageTime.Add(-time.Minute)},
+		},
+		"status": {{Value: "error", EventTime: messageTime}},
+	})
+}
+
+func Test_should_filter_records_by_value_range(t *testing.T) {
+	// Given
+	var min, max = -40.0, 85.0
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterRecords{Points: []string{"temperature"}, Min: &min, Max: &max}
+	inputUpMessage := buildUpMessageForRecordFilter()
+	// When
+	outputUpMessage, err := filterRecordsOperation.ApplyUpOperation(&inputUpMessage, &filterOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{21.5, 22.0}, recordValues(outputUpMessage.Points["temperature"]))
+	assert.Equal(t, inputUpMessage.Points["location"], outputUpMessage.Points["location"])
+	assert.Equal(t, 4, len(inputUpMessage.Points["temperature"].Records))
+}
+
+func Test_should_filter_records_by_event_time_window(t *testing.T) {
+	// Given
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterRecords{Points: []string{"temperature"}, EventTime: &ontology.EventTimeWindow{From: "-24h", To: "0s"}}
+	inputUpMessage := buildUpMessageForRecordFilter()
+	// When
+	outputUpMessage, err := filterRecordsOperation.ApplyUpOperation(&inputUpMessage, &filterOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{-60.0, 22.0, "n/a"}, recordValues(outputUpMessage.Points["temperature"]))
+}
+
+func Test_should_remove_points_left_without_records(t *testing.T) {
+	// Given
+	var hasCoordinates = true
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterRecords{HasCoordinates: &hasCoordinates}
+	inputUpMessage := buildUpMessageForRecordFilter()
+	// When
+	outputUpMessage, err := filterRecordsOperation.ApplyUpOperation(&inputUpMessage, &filterOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"location"}, pointKeys(outputUpMessage))
+	assert.Equal(t, 2, len(outputUpMessage.Points["location"].Records))
+}
+
+func Test_should_filter_records_with_expression(t *testing.T) {
+	// Given
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterRecords{Points: []string{"location"}, Expression: "{{coordinates[0] != `0`}}"}
+	inputUpMessage := buildUpMessageForRecordFilter()
+	// When
+	outputUpMessage, err := filterRecordsOperation.ApplyUpOperation(&inputUpMessage, &filterOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []flow.Record{inputUpMessage.Points["location"].Records[0]}, outputUpMessage.Points["location"].Records)
+}
+
+func Test_should_return_error_when_record_expression_is_not_boolean(t *testing.T) {
+	// Given
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterRecords{Points: []string{"status"}, Expression: "{{value}}"}
+	inputUpMessage := buildUpMessageForRecordFilter()
+	// When
+	_, err := filterRecordsOperation.ApplyUpOperation(&inputUpMessage, &filterOpr)
+	// Then
+	assert.True(t, errors.Is(err, util.ErrUnexpectedResult))
+	assert.EqualError(t, err, "expected a boolean, got error")
+}
+
+func Test_should_report_invalid_record_filter_on_validation(t *testing.T) {
+	// Given
+	var min, max = 10.0, 0.0
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterRecords{Min: &min, Max: &max, EventTime: &ontology.EventTimeWindow{From: "yesterday"}}
+	operations := OperationsUpSerDer{Operations: []ontology.UpOperationInterface{filterOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, len(validationErr.Problems))
+	assert.True(t, errors.Is(err, util.ErrInvalidRule))
+}
@@ -0,0 +1,179 @@
+package operations
+
+import (
+	"context"
+	"ontology-mapping-go-lib/models/flow"
+	"sort"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+const (
+	defaultInsideZonePoint = "insideZone"
+	defaultZoneNamePoint   = "zoneName"
+)
+
+type UpGeofenceOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("geofence", func() ontology.UpOperationInterface { return &ontology.UpGeofence{} }, func() OperationHandler { return &UpGeofenceOperation{} })
+}
+
+func (geofence *UpGeofenceOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return geofence.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
+
+func (geofence *UpGeofenceOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	var geofenceOperation = (*upOperation).(ontology.UpGeofence)
+	zones, err := geofence.zones(ctx, geofenceOperation)
+	if err != nil {
+		return nil, err
+	}
+	var insideZonePoint = pointName(geofenceOperation.InsideZonePoint, defaultInsideZonePoint)
+	var zoneNamePoint = pointName(geofenceOperation.ZoneNamePoint, defaultZoneNamePoint)
+	var keys []string
+	for key := range message.Points {
+		if len(geofenceOperation.Points) == 0 {
+			// the emitted points are not locations, even when mapped again
+			if key != insideZonePoint && key != zoneNamePoint {
+				keys = append(keys, key)
+			}
+		} else if util.Contains(geofenceOperation.Points, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var insideRecords, nameRecords []flow.Record
+	for _, key := range keys {
+		for _, record := range message.Points[key].Records {
+			if len(record.Coordinates) < 2 {
+				continue
+			}
+			var coordinates = append([]float64(nil), record.Coordinates...)
+			var zoneName string
+			for _, zone := range zones {
+				if util.ZoneContains(zone, record.Coordinates[0], record.Coordinates[1]) {
+					zoneName = zone.Name
+					break
+				}
+			}
+			insideRecords = append(insideRecords, flow.Record{Value: len(zoneName) > 0, Coordinates: coordinates, EventTime: record.EventTime})
+			if len(zoneName) > 0 {
+				nameRecords = append(nameRecords, flow.Record{Value: zoneName, Coordinates: coordinates, EventTime: record.EventTime})
+			}
+		}
+	}
+	if len(insideRecords) == 0 {
+		return retMessage, nil
+	}
+	if retMessage.Points == nil {
+		retMessage.Points = make(map[string]flow.Point)
+	}
+	retMessage.Points[insideZonePoint] = flow.Point{Type_: flow.BOOLEAN_Type, Records: sortedRecords(insideRecords)}
+	if len(nameRecords) > 0 {
+		retMessage.Points[zoneNamePoint] = flow.Point{Type_: flow.STRING__Type, Records: sortedRecords(nameRecords)}
+		if len(geofenceOperation.SubType) > 0 {
+			retMessage.SubType = geofenceOperation.SubType
+		}
+	}
+	return retMessage, nil
+}
+
+func (geofence *UpGeofenceOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (geofence *UpGeofenceOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (geofence *UpGeofenceOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var geofenceOperation = upOperation.(ontology.UpGeofence)
+	zones, err := geofenceZones(geofenceOperation)
+	if err != nil {
+		return []*util.MappingError{err.(*util.MappingError)}
+	}
+	var problems []*util.MappingError
+	if len(zones) == 0 {
+		problems = append(problems, util.NewMappingError(util.INVALID_RULE_ErrorCode, "no zone in 'zones' nor in 'geoJson'"))
+	}
+	for _, zone := range zones {
+		if problem := validZone(zone); problem != nil {
+			problems = append(problems, problem)
+		}
+	}
+	if pointName(geofenceOperation.InsideZonePoint, defaultInsideZonePoint) == pointName(geofenceOperation.ZoneNamePoint, defaultZoneNamePoint) {
+		problems = append(problems, util.NewMappingError(util.POINT_COLLISION_ErrorCode, "'insideZonePoint' and 'zoneNamePoint' must differ"))
+	}
+	return problems
+}
+
+func (geofence *UpGeofenceOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+// zones returns the inline zones followed by the GeoJSON ones, checking them
+// all. The inline zones are cheap to check, the GeoJSON ones are parsed and
+// checked once per compiled pipeline.
+func (geofence *UpGeofenceOperation) zones(ctx context.Context, geofenceOperation ontology.UpGeofence) ([]ontology.GeofenceZone, error) {
+	for _, zone := range geofenceOperation.Zones {
+		if err := validZone(zone); err != nil {
+			return nil, err
+		}
+	}
+	if len(geofenceOperation.GeoJson) == 0 {
+		return geofenceOperation.Zones, nil
+	}
+	geoJsonZones, err := compiledRule(ctx, "geofence", string(geofenceOperation.GeoJson), func() (interface{}, error) {
+		zones, err := util.ParseGeoJsonZones(geofenceOperation.GeoJson)
+		if err != nil {
+			return nil, err
+		}
+		for _, zone := range zones {
+			if err := validZone(zone); err != nil {
+				return nil, err
+			}
+		}
+		return zones, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]ontology.GeofenceZone(nil), geofenceOperation.Zones...), geoJsonZones.([]ontology.GeofenceZone)...), nil
+}
+
+func validZone(zone ontology.GeofenceZone) *util.MappingError {
+	if err := util.ValidateZone(zone); err != nil {
+		return &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Key: zone.Name, Message: err.Error()}
+	}
+	return nil
+}
+
+// geofenceZones returns the inline zones followed by the GeoJSON ones.
+func geofenceZones(geofenceOperation ontology.UpGeofence) ([]ontology.GeofenceZone, error) {
+	var zones = geofenceOperation.Zones
+	if len(geofenceOperation.GeoJson) > 0 {
+		geoJsonZones, err := util.ParseGeoJsonZones(geofenceOperation.GeoJson)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(append([]ontology.GeofenceZone(nil), zones...), geoJsonZones...)
+	}
+	return zones, nil
+}
+
+func pointName(name string, defaultName string) string {
+	if len(name) == 0 {
+		return defaultName
+	}
+	return name
+}
+
+func sortedRecords(records []flow.Record) []flow.Record {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].EventTime.Before(records[j].EventTime)
+	})
+	return records
+}
@@ -0,0 +1,175 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+	"time"
+)
+
+var geofenceOperation = UpGeofenceOperation{}
+
+var sophiaAntipolisZone = ontology.GeofenceZone{Name: "sophia", Polygon: [][][]float64{{
+	{7.0, 43.6}, {7.1, 43.6}, {7.1, 43.7}, {7.0, 43.7}, {7.0, 43.6},
+}}}
+
+func buildUpMessageWithLocations() flow.UpMessage {
+	var messageTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	return buildUpMessageWithRecords(map[string][]flow.Record{
+		"coordinates": {
+			{Coordinates: []float64{7.0586624, 43.6618752}, EventTime: messageTime},
+			{Coordinates: []float64{2.3522, 48.8566}, EventTime: messageTime.Add(-time.Hour)},
+		},
+		"temperature": {{Value: 22.0, EventTime: messageTime}},
+	})
+}
+
+func Test_should_emit_inside_zone_and_zone_name_points(t *testing.T) {
+	// Given
+	var geofenceOpr ontology.UpOperationInterface = ontology.UpGeofence{
+		Zones:   []ontology.GeofenceZone{sophiaAntipolisZone, {Name: "paris", Center: []float64{2.3488, 48.8534}, Radius: 1000}},
+		SubType: "zoneEntered",
+	}
+	inputUpMessage := buildUpMessageWithLocations()
+	// When
+	outputUpMessage, err := geofenceOperation.ApplyUpOperation(&inputUpMessage, &geofenceOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{true, true}, recordValues(outputUpMessage.Points["insideZone"]))
+	assert.Equal(t, flow.BOOLEAN_Type, outputUpMessage.Points["insideZone"].Type_)
+	assert.Equal(t, []interface{}{"paris", "sophia"}, recordValues(outputUpMessage.Points["zoneName"]))
+	assert.Equal(t, []float64{2.3522, 48.8566}, outputUpMessage.Points["zoneName"].Records[0].Coordinates)
+	assert.Equal(t, "zoneEntered", outputUpMessage.SubType)
+	assert.Empty(t, inputUpMessage.SubType)
+}
+
+func Test_should_not_name_zone_of_records_outside_every_zone(t *testing.T) {
+	// Given
+	var geofenceOpr ontology.UpOperationInterface = ontology.UpGeofence{
+		Zones:           []ontology.GeofenceZone{{Name: "paris", Center: []float64{2.3488, 48.8534}, Radius: 100}},
+		InsideZonePoint: "inParis",
+		SubType:         "zoneEntered",
+	}
+	inputUpMessage := buildUpMessageWithLocations()
+	// When
+	outputUpMessage, err := geofenceOperation.ApplyUpOperation(&inputUpMessage, &geofenceOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{false, false}, recordValues(outputUpMessage.Points["inParis"]))
+	assert.NotContains(t, outputUpMessage.Points, "zoneName")
+	assert.Empty(t, outputUpMessage.SubType)
+}
+
+func Test_should_load_zones_from_geo_json(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	err := json.Unmarshal([]byte(`{"operations":[{"op":"geofence","points":["coordinates"],"geoJson":{"type":"FeatureCollection","features":[
+		{"type":"Feature","properties":{"name":"campus"},"geometry":{"type":"Polygon","coordinates":[
+			[[7.0,43.6],[7.1,43.6],[7.1,43.7],[7.0,43.7],[7.0,43.6]],
+			[[7.05,43.65],[7.06,43.65],[7.06,43.67],[7.05,43.67],[7.05,43.65]]]}},
+		{"type":"Feature","id":"eiffel","properties":{"radius":5000},"geometry":{"type":"Point","coordinates":[2.2945,48.8584]}}]}}]}`), &operations)
+	assert.Nil(t, err)
+	inputUpMessage := buildUpMessageWithLocations()
+	// When
+	outputUpMessage, err := geofenceOperation.ApplyUpOperation(&inputUpMessage, &operations.Operations[0])
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, operations.Validate())
+	assert.Equal(t, []interface{}{true, false}, recordValues(outputUpMessage.Points["insideZone"]))
+	assert.Equal(t, []interface{}{"eiffel"}, recordValues(outputUpMessage.Points["zoneName"]))
+}
+
+func Test_should_report_invalid_zones(t *testing.T) {
+	// Given
+	var geofenceOpr ontology.UpOperationInterface = ontology.UpGeofence{Zones: []ontology.GeofenceZone{
+		{Name: "line", Polygon: [][][]float64{{{7.0, 43.6}, {7.1, 43.6}, {7.0, 43.6}}}},
+		{Name: "nowhere", Center: []float64{7.0, 93.0}, Radius: 10},
+	}, ZoneNamePoint: "insideZone"}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{geofenceOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 3, len(validationErr.Problems))
+	assert.True(t, errors.Is(err, util.ErrPointCollision))
+	assert.Equal(t, "operation 0 'geofence', key 'line': zone 'line' has a ring of less than 3 distinct positions", validationErr.Problems[1].Error())
+	assert.Equal(t, "operation 0 'geofence', key 'nowhere': zone 'nowhere': position [7 93] is out of range", validationErr.Problems[2].Error())
+}
+
+func Test_should_report_zone_with_repeated_positions(t *testing.T) {
+	// Given
+	var geofenceOpr ontology.UpOperationInterface = ontology.UpGeofence{Zones: []ontology.GeofenceZone{
+		{Name: "point", Polygon: [][][]float64{{{0.0, 0.0}, {0.0, 0.0}, {0.0, 0.0}}}},
+		{Name: "segment", Polygon: [][][]float64{{{7.0, 43.6}, {7.0, 43.6}, {7.1, 43.6}, {7.1, 43.6}}}},
+	}}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{geofenceOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, len(validationErr.Problems))
+	assert.Equal(t, "operation 0 'geofence', key 'point': zone 'point' has a ring of less than 3 distinct positions", validationErr.Problems[0].Error())
+	assert.Equal(t, "operation 0 'geofence', key 'segment': zone 'segment' has a ring of less than 3 distinct positions", validationErr.Problems[1].Error())
+}
+
+func Test_should_return_error_when_applying_invalid_zone(t *testing.T) {
+	// Given
+	var geofenceOpr ontology.UpOperationInterface = ontology.UpGeofence{
+		GeoJson: json.RawMessage(`{"type":"Feature","id":"nowhere","properties":{"radius":10},"geometry":{"type":"Point","coordinates":[7.0,93.0]}}`),
+	}
+	inputUpMessage := buildUpMessageWithLocations()
+	// When
+	_, err := geofenceOperation.ApplyUpOperation(&inputUpMessage, &geofenceOpr)
+	// Then
+	var mappingErr *util.MappingError
+	assert.True(t, errors.As(err, &mappingErr))
+	assert.True(t, errors.Is(err, util.ErrInvalidRule))
+	assert.Equal(t, "nowhere", mappingErr.Key)
+}
+
+func Test_should_parse_geo_json_zones_once(t *testing.T) {
+	// Given
+	var rules = new(compiledRules)
+	var ctx = withCompiledRules(context.Background(), rules)
+	var geofenceOpr ontology.UpOperationInterface = ontology.UpGeofence{
+		GeoJson: json.RawMessage(`{"type":"Feature","id":"eiffel","properties":{"radius":5000},"geometry":{"type":"Point","coordinates":[2.2945,48.8584]}}`),
+	}
+	firstUpMessage := buildUpMessageWithLocations()
+	secondUpMessage := buildUpMessageWithLocations()
+	// When
+	_, firstErr := geofenceOperation.ApplyUpOperationContext(ctx, &firstUpMessage, &geofenceOpr)
+	outputUpMessage, secondErr := geofenceOperation.ApplyUpOperationContext(ctx, &secondUpMessage, &geofenceOpr)
+	// Then
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	var cached int
+	rules.rules.Range(func(key, value interface{}) bool {
+		cached++
+		return true
+	})
+	assert.Equal(t, 1, cached)
+	assert.Equal(t, []interface{}{"eiffel"}, recordValues(outputUpMessage.Points["zoneName"]))
+}
+
+func Test_should_not_read_emitted_points_when_no_points_given(t *testing.T) {
+	// Given
+	var geofenceOpr ontology.UpOperationInterface = ontology.UpGeofence{Zones: []ontology.GeofenceZone{sophiaAntipolisZone}}
+	inputUpMessage := buildUpMessageWithLocations()
+	var messageTime = inputUpMessage.Points["coordinates"].Records[0].EventTime
+	inputUpMessage.Points["insideZone"] = flow.Point{Type_: flow.BOOLEAN_Type, Records: []flow.Record{
+		{Value: true, Coordinates: []float64{7.05, 43.65}, EventTime: messageTime.Add(-2 * time.Hour)},
+	}}
+	// When
+	outputUpMessage, err := geofenceOperation.ApplyUpOperation(&inputUpMessage, &geofenceOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{false, true}, recordValues(outputUpMessage.Points["insideZone"]))
+	assert.Equal(t, []interface{}{"sophia"}, recordValues(outputUpMessage.Points["zoneName"]))
+}
@@ -0,0 +1,105 @@
+package operations
+
+import (
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"reflect"
+	"sort"
+	"time"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type UpNormalizeRecordsOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("normalizeRecords", func() ontology.UpOperationInterface { return &ontology.UpNormalizeRecords{} }, func() OperationHandler { return &UpNormalizeRecordsOperation{} })
+}
+
+func (normalizeRecords *UpNormalizeRecordsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	var normalizeOperation = (*upOperation).(ontology.UpNormalizeRecords)
+	if err := validateNormalization(normalizeOperation); err != nil {
+		return nil, err
+	}
+	for key, point := range retMessage.Points {
+		if len(normalizeOperation.Points) > 0 && !util.Contains(normalizeOperation.Points, key) {
+			continue
+		}
+		var records = point.Records
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].EventTime.Before(records[j].EventTime)
+		})
+		records, err := collapseDuplicates(records, normalizeOperation.Duplicates)
+		if err != nil {
+			return nil, util.WithKey(err, key)
+		}
+		if normalizeOperation.KeepLatest > 0 && len(records) > normalizeOperation.KeepLatest {
+			records = records[len(records)-normalizeOperation.KeepLatest:]
+		}
+		point.Records = records
+		retMessage.Points[key] = point
+	}
+	return retMessage, nil
+}
+
+func (normalizeRecords *UpNormalizeRecordsOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (normalizeRecords *UpNormalizeRecordsOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	if err := validateNormalization(upOperation.(ontology.UpNormalizeRecords)); err != nil {
+		return []*util.MappingError{err.(*util.MappingError)}
+	}
+	return nil
+}
+
+func (normalizeRecords *UpNormalizeRecordsOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+func validateNormalization(normalizeOperation ontology.UpNormalizeRecords) error {
+	switch normalizeOperation.Duplicates {
+	case "", ontology.FIRST_DuplicateStrategy, ontology.LAST_DuplicateStrategy, ontology.ERROR_DuplicateStrategy:
+	default:
+		return util.NewMappingError(util.INVALID_RULE_ErrorCode, fmt.Sprintf("unknown duplicates strategy '%s'", normalizeOperation.Duplicates))
+	}
+	if normalizeOperation.KeepLatest < 0 {
+		return util.NewMappingError(util.INVALID_RULE_ErrorCode, fmt.Sprintf("'keepLatest' %d must not be negative", normalizeOperation.KeepLatest))
+	}
+	return nil
+}
+
+// collapseDuplicates keeps one record per eventTime of the sorted records. With
+// the error strategy identical duplicates are collapsed and differing ones fail.
+func collapseDuplicates(records []flow.Record, strategy ontology.DuplicateStrategy) ([]flow.Record, error) {
+	if len(strategy) == 0 {
+		return records, nil
+	}
+	var collapsed []flow.Record
+	for _, record := range records {
+		var last = len(collapsed) - 1
+		if last < 0 || !collapsed[last].EventTime.Equal(record.EventTime) {
+			collapsed = append(collapsed, record)
+			continue
+		}
+		switch strategy {
+		case ontology.LAST_DuplicateStrategy:
+			collapsed[last] = record
+		case ontology.ERROR_DuplicateStrategy:
+			if !reflect.DeepEqual(collapsed[last].Value, record.Value) || !reflect.DeepEqual(collapsed[last].Coordinates, record.Coordinates) {
+				return nil, util.NewMappingError(util.DUPLICATE_RECORD_ErrorCode, fmt.Sprintf("conflicting records at %s: %v and %v",
+					record.EventTime.Format(time.RFC3339Nano), recordContent(collapsed[last]), recordContent(record)))
+			}
+		}
+	}
+	return collapsed, nil
+}
+
+func recordContent(record flow.Record) interface{} {
+	if record.Value == nil {
+		return record.Coordinates
+	}
+	return record.Value
+}
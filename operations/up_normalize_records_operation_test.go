@@ -0,0 +1,78 @@
+package operations
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+	"time"
+)
+
+var normalizeRecordsOperation = UpNormalizeRecordsOperation{}
+
+func buildUpMessageWithRetransmittedRecords() flow.UpMessage {
+	var messageTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	return buildUpMessageWithRecords(map[string][]flow.Record{
+		"temperature": {
+			{Value: 22.0, EventTime: messageTime},
+			{Value: 20.0, EventTime: messageTime.Add(-2 * time.Minute)},
+			{Value: 21.0, EventTime: messageTime.Add(-time.Minute)},
+			{Value: 21.5, EventTime: messageTime.Add(-time.Minute)},
+			{Value: 20.0, EventTime: messageTime.Add(-2 * time.Minute)},
+		},
+	})
+}
+
+func Test_should_sort_records_and_keep_duplicates_without_strategy(t *testing.T) {
+	// Given
+	var normalizeOpr ontology.UpOperationInterface = ontology.UpNormalizeRecords{}
+	inputUpMessage := buildUpMessageWithRetransmittedRecords()
+	// When
+	outputUpMessage, err := normalizeRecordsOperation.ApplyUpOperation(&inputUpMessage, &normalizeOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{20.0, 20.0, 21.0, 21.5, 22.0}, recordValues(outputUpMessage.Points["temperature"]))
+	assert.Equal(t, 22.0, inputUpMessage.Points["temperature"].Records[0].Value)
+}
+
+func Test_should_collapse_duplicate_records(t *testing.T) {
+	// Given
+	inputUpMessage := buildUpMessageWithRetransmittedRecords()
+	var firstOpr ontology.UpOperationInterface = ontology.UpNormalizeRecords{Duplicates: ontology.FIRST_DuplicateStrategy}
+	var lastOpr ontology.UpOperationInterface = ontology.UpNormalizeRecords{Duplicates: ontology.LAST_DuplicateStrategy, KeepLatest: 2}
+	// When
+	firstUpMessage, firstErr := normalizeRecordsOperation.ApplyUpOperation(&inputUpMessage, &firstOpr)
+	lastUpMessage, lastErr := normalizeRecordsOperation.ApplyUpOperation(&inputUpMessage, &lastOpr)
+	// Then
+	assert.Nil(t, firstErr)
+	assert.Equal(t, []interface{}{20.0, 21.0, 22.0}, recordValues(firstUpMessage.Points["temperature"]))
+	assert.Nil(t, lastErr)
+	assert.Equal(t, []interface{}{21.5, 22.0}, recordValues(lastUpMessage.Points["temperature"]))
+}
+
+func Test_should_return_error_on_conflicting_duplicate_records(t *testing.T) {
+	// Given
+	var normalizeOpr ontology.UpOperationInterface = ontology.UpNormalizeRecords{Duplicates: ontology.ERROR_DuplicateStrategy}
+	inputUpMessage := buildUpMessageWithRetransmittedRecords()
+	// When
+	_, err := normalizeRecordsOperation.ApplyUpOperation(&inputUpMessage, &normalizeOpr)
+	// Then
+	assert.True(t, errors.Is(err, util.ErrDuplicateRecord))
+	assert.EqualError(t, err, "conflicting records at 2020-01-01T09:59:00Z: 21 and 21.5")
+}
+
+func Test_should_collapse_identical_duplicates_with_error_strategy(t *testing.T) {
+	// Given
+	var normalizeOpr ontology.UpOperationInterface = ontology.UpNormalizeRecords{Duplicates: ontology.ERROR_DuplicateStrategy}
+	inputUpMessage := buildUpMessageWithRetransmittedRecords()
+	var point = inputUpMessage.Points["temperature"]
+	point.Records = append(point.Records[:3], point.Records[4])
+	inputUpMessage.Points["temperature"] = point
+	// When
+	outputUpMessage, err := normalizeRecordsOperation.ApplyUpOperation(&inputUpMessage, &normalizeOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{20.0, 21.0, 22.0}, recordValues(outputUpMessage.Points["temperature"]))
+}
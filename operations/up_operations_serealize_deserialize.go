@@ -2,7 +2,6 @@ package operations
 
 import (
 	"encoding/json"
-	"errors"
 	"ontology-mapping-go-lib/models/ontology"
 )
 
@@ -18,26 +17,14 @@ func (opr *OperationsUpSerDer) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	for _, raw := range opr.RawOperations {
+	for index, raw := range opr.RawOperations {
 		var operation ontology.UpOperation
 		err = json.Unmarshal(raw, &operation)
 		if err != nil {
 			return err
 		}
 		var i ontology.UpOperationInterface
-		switch operation.Op {
-		case "extractPoints":
-			i = &ontology.UpExtractPoints{}
-		case "updatePoints":
-			i = &ontology.UpUpdatePoints{}
-		case "filter":
-			i = &ontology.UpFilterOperation{}
-		case "filterPoints":
-			i = &ontology.UpFilterPointsOperation{}
-		default:
-			return errors.New("unknown operation type")
-		}
-		err = json.Unmarshal(raw, i)
+		i, err = decodeUpOperation(index, operation.Op, raw)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,177 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type UpRenamePointsOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("renamePoints", func() ontology.UpOperationInterface { return &ontology.UpRenamePoints{} }, func() OperationHandler { return &UpRenamePointsOperation{} })
+}
+
+func (renamePoints *UpRenamePointsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return renamePoints.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
+
+func (renamePoints *UpRenamePointsOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	if len(retMessage.Points) == 0 {
+		return retMessage, nil
+	}
+	var renameOperation = (*upOperation).(ontology.UpRenamePoints)
+	var keys []string
+	for key := range retMessage.Points {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var newPoints = make(map[string]flow.Point)
+	var sources = make(map[string]string)
+	for _, key := range keys {
+		newKey, err := renamePoints.renamePointKey(ctx, key, renameOperation)
+		if err != nil {
+			return nil, util.WithKey(err, key)
+		}
+		if source, ok := sources[newKey]; ok {
+			return nil, &util.MappingError{Code: util.POINT_COLLISION_ErrorCode, Key: newKey,
+				Message: fmt.Sprintf("points '%s' and '%s' would both be named '%s'", source, key, newKey)}
+		}
+		sources[newKey] = key
+		newPoints[newKey] = retMessage.Points[key]
+	}
+	retMessage.Points = newPoints
+	return retMessage, nil
+}
+
+func (renamePoints *UpRenamePointsOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (renamePoints *UpRenamePointsOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (renamePoints *UpRenamePointsOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var problems []*util.MappingError
+	var renameOperation = upOperation.(ontology.UpRenamePoints)
+	for key, newKey := range renameOperation.Points {
+		if len(newKey) == 0 {
+			problems = append(problems, &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Key: key, Message: "the new point name must not be empty"})
+		}
+	}
+	for index, rule := range renameOperation.Rules {
+		if _, err := renamePoints.compileMatch(context.Background(), rule); err != nil {
+			problems = append(problems, &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Key: fmt.Sprintf("rules[%d]", index), Message: err.Error(), Err: err})
+		}
+	}
+	return problems
+}
+
+func (renamePoints *UpRenamePointsOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+// renamePointKey applies the explicit pair of key if any, the rules otherwise.
+func (renamePoints *UpRenamePointsOperation) renamePointKey(ctx context.Context, key string, renameOperation ontology.UpRenamePoints) (string, error) {
+	if newKey, ok := renameOperation.Points[key]; ok {
+		if len(newKey) == 0 {
+			return "", util.NewMappingError(util.INVALID_RULE_ErrorCode, "the new point name must not be empty")
+		}
+		return newKey, nil
+	}
+	var newKey = key
+	for index, rule := range renameOperation.Rules {
+		match, err := renamePoints.compileMatch(ctx, rule)
+		if err != nil {
+			return "", &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Message: fmt.Sprintf("rules[%d]: %v", index, err), Err: err}
+		}
+		if match != nil {
+			if !match.MatchString(newKey) {
+				continue
+			}
+			if rule.Replace != nil {
+				newKey = match.ReplaceAllString(newKey, *rule.Replace)
+			}
+		}
+		switch rule.Case {
+		case ontology.SNAKE_TO_CAMEL_PointKeyCase:
+			newKey = snakeToCamel(newKey)
+		case ontology.CAMEL_TO_SNAKE_PointKeyCase:
+			newKey = camelToSnake(newKey)
+		}
+		newKey = rule.Prefix + newKey + rule.Suffix
+	}
+	if len(newKey) == 0 {
+		return "", util.NewMappingError(util.INVALID_RULE_ErrorCode, "the rules give an empty point name")
+	}
+	return newKey, nil
+}
+
+// compileMatch returns the compiled 'match' pattern of rule, nil when it has none.
+// A compiled pipeline compiles each pattern once.
+func (renamePoints *UpRenamePointsOperation) compileMatch(ctx context.Context, rule ontology.RenamePointsRule) (*regexp.Regexp, error) {
+	if err := validateRenameRule(rule); err != nil || len(rule.Match) == 0 {
+		return nil, err
+	}
+	match, err := compiledRule(ctx, "renamePoints", rule.Match, func() (interface{}, error) {
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'match' pattern: %v", err)
+		}
+		return match, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return match.(*regexp.Regexp), nil
+}
+
+func validateRenameRule(rule ontology.RenamePointsRule) error {
+	switch rule.Case {
+	case "", ontology.SNAKE_TO_CAMEL_PointKeyCase, ontology.CAMEL_TO_SNAKE_PointKeyCase:
+		return nil
+	default:
+		return fmt.Errorf("unknown case '%s'", rule.Case)
+	}
+}
+
+func snakeToCamel(key string) string {
+	var words = strings.Split(key, "_")
+	var builder strings.Builder
+	for _, word := range words {
+		if len(word) == 0 {
+			continue
+		}
+		if builder.Len() == 0 {
+			builder.WriteString(word)
+			continue
+		}
+		var runes = []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		builder.WriteString(string(runes))
+	}
+	return builder.String()
+}
+
+func camelToSnake(key string) string {
+	var builder strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				builder.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
@@ -0,0 +1,129 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+)
+
+var renamePointsOperation = UpRenamePointsOperation{}
+
+func pointKeys(message *flow.UpMessage) []string {
+	var keys []string
+	for key := range message.Points {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func Test_should_rename_points_with_explicit_pairs_and_rules(t *testing.T) {
+	// Given
+	var renameOpr ontology.UpOperationInterface = ontology.UpRenamePoints{
+		Points: map[string]string{"temp": "temperature"},
+		Rules: []ontology.RenamePointsRule{
+			{Case: ontology.SNAKE_TO_CAMEL_PointKeyCase},
+			{Match: "^battery", Prefix: "device."},
+		},
+	}
+	inputUpMessage := buildUpMessageWithPoint("temp", flow.DOUBLE_Type, 22.5)
+	inputUpMessage.Points["battery_level"] = buildUpMessageWithPoint("battery_level", flow.DOUBLE_Type, 90.0).Points["battery_level"]
+	inputUpMessage.Points["signal_strength"] = buildUpMessageWithPoint("signal_strength", flow.DOUBLE_Type, -80.0).Points["signal_strength"]
+	// When
+	outputUpMessage, err := renamePointsOperation.ApplyUpOperation(&inputUpMessage, &renameOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"temperature", "device.batteryLevel", "signalStrength"}, pointKeys(outputUpMessage))
+	assert.Equal(t, inputUpMessage.Points["temp"], outputUpMessage.Points["temperature"])
+	assert.ElementsMatch(t, []string{"temp", "battery_level", "signal_strength"}, pointKeys(&inputUpMessage))
+}
+
+func Test_should_rename_points_with_regular_expression(t *testing.T) {
+	// Given
+	var replace = "temperature_$1"
+	var renameOpr ontology.UpOperationInterface = ontology.UpRenamePoints{Rules: []ontology.RenamePointsRule{
+		{Match: "^ch(\\d+)Temperature$", Replace: &replace, Case: ontology.CAMEL_TO_SNAKE_PointKeyCase},
+	}}
+	inputUpMessage := buildUpMessageWithPoint("ch1Temperature", flow.DOUBLE_Type, 22.5)
+	inputUpMessage.Points["batteryLevel"] = buildUpMessageWithPoint("batteryLevel", flow.DOUBLE_Type, 90.0).Points["batteryLevel"]
+	// When
+	outputUpMessage, err := renamePointsOperation.ApplyUpOperation(&inputUpMessage, &renameOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"temperature_1", "batteryLevel"}, pointKeys(outputUpMessage))
+}
+
+func Test_should_strip_match_replaced_by_empty_string(t *testing.T) {
+	// Given
+	var strip = ""
+	var renameOpr ontology.UpOperationInterface = ontology.UpRenamePoints{Rules: []ontology.RenamePointsRule{
+		{Match: "^sensor_", Replace: &strip},
+	}}
+	var rules = new(compiledRules)
+	var ctx = withCompiledRules(context.Background(), rules)
+	inputUpMessage := buildUpMessageWithPoint("sensor_temperature", flow.DOUBLE_Type, 22.5)
+	inputUpMessage.Points["sensor_humidity"] = buildUpMessageWithPoint("sensor_humidity", flow.DOUBLE_Type, 40.0).Points["sensor_humidity"]
+	// When
+	outputUpMessage, err := renamePointsOperation.ApplyUpOperationContext(ctx, &inputUpMessage, &renameOpr)
+	secondOutputUpMessage, secondErr := renamePointsOperation.ApplyUpOperationContext(ctx, &inputUpMessage, &renameOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, secondErr)
+	assert.ElementsMatch(t, []string{"temperature", "humidity"}, pointKeys(outputUpMessage))
+	assert.Equal(t, outputUpMessage, secondOutputUpMessage)
+	var patterns int
+	rules.rules.Range(func(key, value interface{}) bool {
+		patterns++
+		return true
+	})
+	assert.Equal(t, 1, patterns)
+}
+
+func Test_should_return_error_when_new_point_name_is_empty(t *testing.T) {
+	// Given
+	var strip = ""
+	var explicitOpr ontology.UpOperationInterface = ontology.UpRenamePoints{Points: map[string]string{"temp": ""}}
+	var ruleOpr ontology.UpOperationInterface = ontology.UpRenamePoints{Rules: []ontology.RenamePointsRule{{Match: ".*", Replace: &strip}}}
+	inputUpMessage := buildUpMessageWithPoint("temp", flow.DOUBLE_Type, 22.5)
+	// When
+	_, explicitErr := renamePointsOperation.ApplyUpOperation(&inputUpMessage, &explicitOpr)
+	_, ruleErr := renamePointsOperation.ApplyUpOperation(&inputUpMessage, &ruleOpr)
+	// Then
+	assert.True(t, errors.Is(explicitErr, util.ErrInvalidRule))
+	assert.Equal(t, "temp", explicitErr.(*util.MappingError).Key)
+	assert.EqualError(t, explicitErr, "the new point name must not be empty")
+	assert.True(t, errors.Is(ruleErr, util.ErrInvalidRule))
+	assert.EqualError(t, ruleErr, "the rules give an empty point name")
+}
+
+func Test_should_return_error_when_renamed_points_collide(t *testing.T) {
+	// Given
+	var renameOpr ontology.UpOperationInterface = ontology.UpRenamePoints{Points: map[string]string{"temp": "temperature"}}
+	inputUpMessage := buildUpMessageWithPoint("temp", flow.DOUBLE_Type, 22.5)
+	inputUpMessage.Points["temperature"] = buildUpMessageWithPoint("temperature", flow.DOUBLE_Type, 23.0).Points["temperature"]
+	// When
+	outputUpMessage, err := renamePointsOperation.ApplyUpOperation(&inputUpMessage, &renameOpr)
+	// Then
+	assert.Nil(t, outputUpMessage)
+	assert.True(t, errors.Is(err, util.ErrPointCollision))
+	assert.EqualError(t, err, "points 'temp' and 'temperature' would both be named 'temperature'")
+}
+
+func Test_should_report_invalid_rename_rules_on_validation(t *testing.T) {
+	// Given
+	var renameOpr ontology.UpOperationInterface = ontology.UpRenamePoints{Rules: []ontology.RenamePointsRule{
+		{Match: "("},
+		{Case: "kebab"},
+	}}
+	operations := OperationsUpSerDer{Operations: []ontology.UpOperationInterface{renameOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, len(validationErr.Problems))
+	assert.Equal(t, "operation 0 'renamePoints', key 'rules[1]': unknown case 'kebab'", validationErr.Problems[1].Error())
+}
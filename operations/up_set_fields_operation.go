@@ -0,0 +1,231 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"sort"
+	"strings"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type UpSetFieldsOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("setFields", func() ontology.UpOperationInterface { return &ontology.UpSetFields{} }, func() OperationHandler { return &UpSetFieldsOperation{} })
+}
+
+// fieldTarget is a parsed setFields target, path is the location of the value
+// within the content.
+type fieldTarget struct {
+	field  string
+	path   []string
+	append bool
+}
+
+const (
+	subTypeField = "subType"
+	tagsField    = "thing/tags"
+	contentField = "content"
+)
+
+func (setFields *UpSetFieldsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return setFields.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
+
+// ApplyUpOperationContext evaluates every template on the incoming message, a
+// template returning null leaves its target unchanged.
+func (setFields *UpSetFieldsOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var retMessage = util.CopyUpMessage(message)
+	var fields = (*upOperation).(ontology.UpSetFields).Fields
+	var targets []string
+	for target := range fields {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	messageJson, err := jsonView(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		parsed, err := parseFieldTarget(target)
+		if err != nil {
+			return nil, util.WithKey(err, target)
+		}
+		var value = fields[target]
+		if template, ok := value.(string); ok && util.IsJmesExpression(template) {
+			value, err = util.RetrieveValuesContext(ctx, template, &messageJson)
+			if err != nil {
+				return nil, util.WithKey(err, target)
+			}
+			if value == nil {
+				continue
+			}
+		}
+		// values are copied as a later content target may write into them, and
+		// template results belong to the shared view of the message
+		value = util.CloneValue(value)
+		if err = setField(retMessage, parsed, value); err != nil {
+			return nil, util.WithKey(err, target)
+		}
+	}
+	return retMessage, nil
+}
+
+func (setFields *UpSetFieldsOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (setFields *UpSetFieldsOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func (setFields *UpSetFieldsOperation) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	var expressions []string
+	for _, value := range upOperation.(ontology.UpSetFields).Fields {
+		if template, ok := value.(string); ok {
+			expressions = append(expressions, template)
+		}
+	}
+	return expressions
+}
+
+func (setFields *UpSetFieldsOperation) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	return nil
+}
+
+func (setFields *UpSetFieldsOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var fields = upOperation.(ontology.UpSetFields).Fields
+	if len(fields) == 0 {
+		return []*util.MappingError{util.NewMappingError(util.INVALID_FIELD_ErrorCode, "'fields' must not be empty")}
+	}
+	var problems []*util.MappingError
+	for target, value := range fields {
+		if _, err := parseFieldTarget(target); err != nil {
+			problems = append(problems, util.WithKey(err, target).(*util.MappingError))
+			continue
+		}
+		if template, ok := value.(string); ok {
+			problems = append(problems, validateExpressions(target, []string{template})...)
+		}
+	}
+	return problems
+}
+
+func (setFields *UpSetFieldsOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
+
+// parseFieldTarget checks target against the writable fields: "/subType",
+// "/thing/tags" replacing the tags, "/thing/tags/-" appending to them and
+// "/content" or any object member below it. "~1" and "~0" escape '/' and '~'.
+func parseFieldTarget(target string) (fieldTarget, error) {
+	var tokens []string
+	if strings.HasPrefix(target, "/") {
+		tokens = strings.Split(target[1:], "/")
+	}
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	switch {
+	case len(tokens) == 1 && tokens[0] == subTypeField:
+		return fieldTarget{field: subTypeField}, nil
+	case len(tokens) == 2 && tokens[0] == "thing" && tokens[1] == "tags":
+		return fieldTarget{field: tagsField}, nil
+	case len(tokens) == 3 && tokens[0] == "thing" && tokens[1] == "tags" && tokens[2] == "-":
+		return fieldTarget{field: tagsField, append: true}, nil
+	case len(tokens) > 0 && tokens[0] == contentField:
+		for _, token := range tokens[1:] {
+			if len(token) == 0 {
+				return fieldTarget{}, util.NewMappingError(util.INVALID_FIELD_ErrorCode, "empty member name in content target")
+			}
+		}
+		return fieldTarget{field: contentField, path: tokens[1:]}, nil
+	}
+	return fieldTarget{}, util.NewMappingError(util.INVALID_FIELD_ErrorCode,
+		"not a writable field, expected '/subType', '/thing/tags', '/thing/tags/-' or '/content[/member...]'")
+}
+
+func setField(message *flow.UpMessage, target fieldTarget, value interface{}) error {
+	switch target.field {
+	case subTypeField:
+		subType, err := util.ConvertValue(value, flow.STRING__Type)
+		if err != nil {
+			return &util.MappingError{Code: util.UNEXPECTED_RESULT_ErrorCode, Message: fmt.Sprintf("expected a string, got %v", value), Err: err}
+		}
+		message.SubType = subType.(string)
+	case tagsField:
+		tags, err := toTags(value)
+		if err != nil {
+			return err
+		}
+		if message.Thing == nil {
+			message.Thing = &flow.Thing{}
+		}
+		if !target.append {
+			message.Thing.Tags = tags
+			return nil
+		}
+		for _, tag := range tags {
+			if !util.Contains(message.Thing.Tags, tag) {
+				message.Thing.Tags = append(message.Thing.Tags, tag)
+			}
+		}
+	default:
+		return setContent(message, target.path, value)
+	}
+	return nil
+}
+
+// toTags accepts a single tag or an array of tags.
+func toTags(value interface{}) ([]string, error) {
+	var values []interface{}
+	switch typed := value.(type) {
+	case []interface{}:
+		values = typed
+	case []string:
+		for _, tag := range typed {
+			values = append(values, tag)
+		}
+	default:
+		values = []interface{}{value}
+	}
+	var tags = make([]string, 0, len(values))
+	for _, value := range values {
+		tag, err := util.ConvertValue(value, flow.STRING__Type)
+		if err != nil {
+			return nil, &util.MappingError{Code: util.UNEXPECTED_RESULT_ErrorCode, Message: fmt.Sprintf("expected a tag or an array of tags, got %v", value), Err: err}
+		}
+		tags = append(tags, tag.(string))
+	}
+	return tags, nil
+}
+
+// setContent sets value at path within the content, creating the missing objects
+// on the way.
+func setContent(message *flow.UpMessage, path []string, value interface{}) error {
+	if len(path) == 0 {
+		message.Content = value
+		return nil
+	}
+	if message.Content == nil {
+		message.Content = make(map[string]interface{})
+	}
+	var parent, ok = message.Content.(map[string]interface{})
+	for i, member := range path {
+		if !ok {
+			return util.NewMappingError(util.INVALID_FIELD_ErrorCode, fmt.Sprintf("'/%s' is not an object", strings.Join(append([]string{contentField}, path[:i]...), "/")))
+		}
+		if i == len(path)-1 {
+			parent[member] = value
+			break
+		}
+		if parent[member] == nil {
+			parent[member] = make(map[string]interface{})
+		}
+		parent, ok = parent[member].(map[string]interface{})
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package operations
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+)
+
+var setFieldsOperation = UpSetFieldsOperation{}
+
+func Test_should_set_sub_type_and_append_tags(t *testing.T) {
+	// Given
+	var setFieldsOpr ontology.UpOperationInterface = ontology.UpSetFields{Fields: map[string]interface{}{
+		"/subType":       "measurement",
+		"/thing/tags/-":  []interface{}{"outdoor", "sensor"},
+		"/thing/tags":    "sensor",
+		"/content/empty": "{{packet.message.humidity}}",
+	}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	inputUpMessage.Thing.Tags = []string{"legacy"}
+	// When
+	outputUpMessage, err := setFieldsOperation.ApplyUpOperation(&inputUpMessage, &setFieldsOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "measurement", outputUpMessage.SubType)
+	assert.Equal(t, []string{"sensor", "outdoor"}, outputUpMessage.Thing.Tags)
+	assert.Nil(t, outputUpMessage.Content)
+	assert.Equal(t, []string{"legacy"}, inputUpMessage.Thing.Tags)
+}
+
+func Test_should_set_content_members_from_templates(t *testing.T) {
+	// Given
+	var setFieldsOpr ontology.UpOperationInterface = ontology.UpSetFields{Fields: map[string]interface{}{
+		"/content/site/name":        "building A",
+		"/content/site/temperature": "{{packet.message.temperature}}",
+		"/content/a~1b":             true,
+		"/thing/tags/-":             "{{thing.key}}",
+	}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	outputUpMessage, err := setFieldsOperation.ApplyUpOperation(&inputUpMessage, &setFieldsOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"site": map[string]interface{}{"name": "building A", "temperature": 22.6},
+		"a/b":  true,
+	}, outputUpMessage.Content)
+	assert.Equal(t, []string{"lora:0102030405060708"}, outputUpMessage.Thing.Tags)
+}
+
+func Test_should_evaluate_templates_on_json_form_of_message(t *testing.T) {
+	// Given
+	var setFieldsOpr ontology.UpOperationInterface = ontology.UpSetFields{Fields: map[string]interface{}{
+		"/content/type": "{{type}}",
+		"/content/time": "{{time}}",
+	}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	outputUpMessage, err := setFieldsOperation.ApplyUpOperation(&inputUpMessage, &setFieldsOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"type": "deviceUplink", "time": "2020-01-01T10:00:00Z"}, outputUpMessage.Content)
+}
+
+func Test_should_not_share_constants_between_messages(t *testing.T) {
+	// Given
+	var fields = map[string]interface{}{"/content": map[string]interface{}{"a": 1.0}, "/content/b": "{{id}}"}
+	var setFieldsOpr ontology.UpOperationInterface = ontology.UpSetFields{Fields: fields}
+	firstMessage := buildInputUpMessage("include_existing_points.json")
+	secondMessage := buildInputUpMessage("include_existing_points.json")
+	secondMessage.Id = "second"
+	// When
+	firstOutput, firstErr := setFieldsOperation.ApplyUpOperation(&firstMessage, &setFieldsOpr)
+	secondOutput, secondErr := setFieldsOperation.ApplyUpOperation(&secondMessage, &setFieldsOpr)
+	// Then
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	assert.Equal(t, map[string]interface{}{"a": 1.0, "b": firstMessage.Id}, firstOutput.Content)
+	assert.Equal(t, map[string]interface{}{"a": 1.0, "b": "second"}, secondOutput.Content)
+	assert.Equal(t, map[string]interface{}{"a": 1.0}, fields["/content"])
+}
+
+func Test_should_fail_to_set_member_of_non_object_content(t *testing.T) {
+	// Given
+	var setFieldsOpr ontology.UpOperationInterface = ontology.UpSetFields{Fields: map[string]interface{}{
+		"/content/site/name": "building A",
+	}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	inputUpMessage.Content = map[string]interface{}{"site": "building A"}
+	// When
+	_, err := setFieldsOperation.ApplyUpOperation(&inputUpMessage, &setFieldsOpr)
+	// Then
+	assert.True(t, errors.Is(err, util.ErrInvalidField))
+	assert.Equal(t, "'/content/site' is not an object", err.Error())
+}
+
+func Test_should_report_fields_that_are_not_writable(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	_ = json.Unmarshal([]byte(`{"operations":[{"op":"setFields","fields":{
+		"/subType":"{{packet.message.[type}}",
+		"/thing/key":"other",
+		"/points/temperature":22.5}}]}`), &operations)
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 3, len(validationErr.Problems))
+	assert.Equal(t, util.INVALID_FIELD_ErrorCode, validationErr.Problems[0].Code)
+	assert.Equal(t, "/points/temperature", validationErr.Problems[0].Key)
+	assert.Equal(t, util.INVALID_EXPRESSION_ErrorCode, validationErr.Problems[1].Code)
+	assert.Equal(t, "/thing/key", validationErr.Problems[2].Key)
+}
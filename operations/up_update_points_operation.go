@@ -1,7 +1,7 @@
 package operations
 
 import (
-	"errors"
+	"context"
 	"ontology-mapping-go-lib/models/flow"
 	"strings"
 	"time"
@@ -12,7 +12,15 @@ import "ontology-mapping-go-lib/util"
 type UpUpdatePointsOperation struct {
 }
 
+func init() {
+	MustRegisterUpOperation("updatePoints", func() ontology.UpOperationInterface { return &ontology.UpUpdatePoints{} }, func() OperationHandler { return &UpUpdatePointsOperation{} })
+}
+
 func (updatePointsOperation *UpUpdatePointsOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return updatePointsOperation.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
+
+func (updatePointsOperation *UpUpdatePointsOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
 	var retMessage = util.CopyUpMessage(message)
 	var existingPoints = message.Points
 	var newPoints = make(map[string]flow.Point)
@@ -34,17 +42,17 @@ func (updatePointsOperation *UpUpdatePointsOperation) ApplyUpOperation(message *
 			for i := 0; i < len(records); i++ {
 				if records[i].Value != nil {
 					var retVal interface{}
-					retVal, err = getValue(records[i].Value, value.Value)
+					retVal, err = getValue(ctx, records[i].Value, value.Value)
 					if err != nil {
-						return nil, err
+						return nil, util.WithKey(err, key)
 					}
 					values = append(values, retVal)
 					isValue = true
 				}
 				var retTime interface{}
-				retTime, err = getEventTime(records[i].EventTime, value.EventTime)
+				retTime, err = getEventTime(ctx, records[i].EventTime, value.EventTime)
 				if err != nil {
-					return nil, err
+					return nil, util.WithKey(err, key)
 				}
 				eventTimes = append(eventTimes, retTime)
 				if records[i].Coordinates != nil && len(records[i].Coordinates) > 0 {
@@ -59,10 +67,10 @@ func (updatePointsOperation *UpUpdatePointsOperation) ApplyUpOperation(message *
 						if len(value.Coordinates) == 2 {
 							lng = records[i].Coordinates[0]
 							lat = records[i].Coordinates[1]
-							resLng, err = util.RetrieveValues(value.Coordinates[0], &lng)
-							resLat, err = util.RetrieveValues(value.Coordinates[1], &lat)
+							resLng, err = util.RetrieveValuesContext(ctx, value.Coordinates[0], &lng)
+							resLat, err = util.RetrieveValuesContext(ctx, value.Coordinates[1], &lat)
 							if err != nil {
-								return nil, err
+								return nil, util.WithKey(err, key)
 							}
 							longitudes = append(longitudes, resLng)
 							latitudes = append(latitudes, resLat)
@@ -70,19 +78,19 @@ func (updatePointsOperation *UpUpdatePointsOperation) ApplyUpOperation(message *
 							lng = records[i].Coordinates[0]
 							lat = records[i].Coordinates[1]
 							alt = records[i].Coordinates[2]
-							resLng, err = util.RetrieveValues(value.Coordinates[0], &lng)
-							resLat, err = util.RetrieveValues(value.Coordinates[1], &lat)
-							resAlt, err = util.RetrieveValues(value.Coordinates[2], &alt)
+							resLng, err = util.RetrieveValuesContext(ctx, value.Coordinates[0], &lng)
+							resLat, err = util.RetrieveValuesContext(ctx, value.Coordinates[1], &lat)
+							resAlt, err = util.RetrieveValuesContext(ctx, value.Coordinates[2], &alt)
 							if err != nil {
-								return nil, err
+								return nil, util.WithKey(err, key)
 							}
 							longitudes = append(longitudes, resLng)
 							latitudes = append(latitudes, resLat)
 							altitudes = append(altitudes, resAlt)
 							isAltitude = true
 						} else {
-							err = errors.New("invalid 'coordinate' length, it must be 2 or 3 %s" + key)
-							return nil, err
+							err = &util.MappingError{Code: util.INVALID_COORDINATES_ErrorCode, Key: key, Message: "invalid 'coordinate' length, it must be 2 or 3"}
+							return nil, util.WithKey(err, key)
 						}
 					} else {
 						lng = records[i].Coordinates[0]
@@ -97,11 +105,12 @@ func (updatePointsOperation *UpUpdatePointsOperation) ApplyUpOperation(message *
 					}
 				}
 			}
-			var params = util.PointParams{Values: values, EventTime: eventTimes, Longitude: longitudes, Latitude: latitudes, Altitude: altitudes, IsAltitude: isAltitude, IsValue: isValue, IsCoordinate: isCoordinate}
+			var params = util.PointParams{Values: values, EventTime: eventTimes, Longitude: longitudes, Latitude: latitudes, Altitude: altitudes, IsAltitude: isAltitude, IsValue: isValue, IsCoordinate: isCoordinate,
+				EventTimeFormat: value.EventTimeFormat, Timezone: value.Timezone}
 			var newRecords []flow.Record
-			newRecords, err = util.ExtractRecords(params, key)
+			newRecords, err = util.ExtractRecordsContext(ctx, params, key)
 			if err != nil {
-				return nil, err
+				return nil, util.WithKey(err, key)
 			}
 			var assignpointType flow.PointType
 			var pointType string
@@ -133,6 +142,15 @@ func (updatePointsOperation *UpUpdatePointsOperation) ApplyUpOperation(message *
 		}
 
 	}
+	if jmesPathOperation.ConvertValues {
+		var keys []string
+		for key := range jmesPathOperation.Points {
+			keys = append(keys, key)
+		}
+		if err = convertPointValues(newPoints, keys); err != nil {
+			return nil, err
+		}
+	}
 	if len(newPoints) > 0 {
 		retMessage.Points = newPoints
 	}
@@ -143,9 +161,13 @@ func (updatePointsOperation *UpUpdatePointsOperation) ApplyDownOperation(message
 	return nil, nil
 }
 
-func getValue(recordVal interface{}, jmesPathValue string) (interface{}, error) {
+func (updatePointsOperation *UpUpdatePointsOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return nil, nil
+}
+
+func getValue(ctx context.Context, recordVal interface{}, jmesPathValue string) (interface{}, error) {
 	if &jmesPathValue != nil && strings.Contains(jmesPathValue, "{{") && strings.Contains(jmesPathValue, "}}") {
-		return util.RetrieveValues(jmesPathValue, &recordVal)
+		return util.RetrieveValuesContext(ctx, jmesPathValue, &recordVal)
 	} else if &jmesPathValue != nil && len(jmesPathValue) > 0 {
 		var retVal interface{} = jmesPathValue
 		return retVal, nil
@@ -153,10 +175,10 @@ func getValue(recordVal interface{}, jmesPathValue string) (interface{}, error)
 		return recordVal, nil
 	}
 }
-func getEventTime(recordTime time.Time, jmesPathEventTime string) (interface{}, error) {
+func getEventTime(ctx context.Context, recordTime time.Time, jmesPathEventTime string) (interface{}, error) {
 	var recTime interface{} = recordTime
 	if &jmesPathEventTime != nil && strings.Contains(jmesPathEventTime, "{{") && strings.Contains(jmesPathEventTime, "}}") {
-		return util.RetrieveValues(jmesPathEventTime, &recTime)
+		return util.RetrieveValuesContext(ctx, jmesPathEventTime, &recTime)
 	} else if &jmesPathEventTime != nil && len(jmesPathEventTime) > 0 {
 		recTime = jmesPathEventTime
 		return recTime, nil
@@ -165,3 +187,33 @@ func getEventTime(recordTime time.Time, jmesPathEventTime string) (interface{},
 		return recordTime, nil
 	}
 }
+
+func (updatePointsOperation *UpUpdatePointsOperation) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	var expressions []string
+	for _, point := range upOperation.(ontology.UpUpdatePoints).Points {
+		expressions = append(expressions, point.Value, point.EventTime)
+		expressions = append(expressions, point.Coordinates...)
+	}
+	return expressions
+}
+
+func (updatePointsOperation *UpUpdatePointsOperation) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	return nil
+}
+
+func (updatePointsOperation *UpUpdatePointsOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var jmesPathOperation = upOperation.(ontology.UpUpdatePoints)
+	if len(jmesPathOperation.Points) == 0 {
+		return []*util.MappingError{util.NewMappingError(util.EMPTY_POINTS_ErrorCode, "'points' must not be empty")}
+	}
+	var problems []*util.MappingError
+	for key, element := range jmesPathOperation.Points {
+		problems = append(problems, validatePoint(key, element.Value, element.EventTime, element.Coordinates, element.Type_)...)
+		problems = append(problems, validateEventTimeFormat(key, element.EventTimeFormat, element.Timezone)...)
+	}
+	return problems
+}
+
+func (updatePointsOperation *UpUpdatePointsOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	return nil
+}
@@ -397,3 +397,20 @@ func Test_should_not_update_when_there_is_a_mismatch_points_from_update_points(t
 	expectedOutputMessage.Points = expectedPoints
 	assert.Equal(t, outputUpMessage, expectedOutputMessage)
 }
+
+func Test_should_read_updated_event_time_in_configured_format(t *testing.T) {
+	// Given
+	var eventTime, _ = time.Parse(time.RFC3339, "2020-01-01T10:00:00.000Z")
+	inputUpMessage := buildInputUpUpdateMessage(map[string]flow.Point{
+		"temperature": {Type_: "double", UnitId: "Cel", Records: []flow.Record{{Value: 22.6, EventTime: eventTime}}},
+	})
+	var updateOpr ontology.UpOperationInterface = ontology.UpUpdatePoints{Points: map[string]ontology.JmesPathUpdatePoint{
+		"temperature": {EventTime: "2021-03-09 11:15:00", EventTimeFormat: "2006-01-02 15:04:05", Timezone: "Europe/Paris"},
+	}}
+	// When
+	outputUpMessage, err := jmesPathUpdateOperation.ApplyUpOperation(&inputUpMessage, &updateOpr)
+	// Then
+	assert.Nil(t, err)
+	var expectedTime, _ = time.Parse(time.RFC3339, "2021-03-09T10:15:00Z")
+	assert.True(t, expectedTime.Equal(outputUpMessage.Points["temperature"].Records[0].EventTime))
+}
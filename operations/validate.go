@@ -0,0 +1,196 @@
+package operations
+
+import (
+	"fmt"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists every problem found in a mapping by Validate.
+type ValidationError struct {
+	Problems []*util.MappingError
+}
+
+func (validationError *ValidationError) Error() string {
+	var messages []string
+	for _, problem := range validationError.Problems {
+		messages = append(messages, problem.Error())
+	}
+	return fmt.Sprintf("%d problem(s) found in mapping: %s", len(messages), strings.Join(messages, "; "))
+}
+
+// Is matches any of the problems, so errors.Is(err, util.ErrInvalidExpression)
+// tells whether the mapping holds an invalid expression.
+func (validationError *ValidationError) Is(target error) bool {
+	for _, problem := range validationError.Problems {
+		if problem.Is(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks every operation before any message is processed and reports all
+// the problems found at once, it returns nil when the mapping is valid.
+func (opr *OperationsUpSerDer) Validate() error {
+	var problems []*util.MappingError
+	for index, operation := range opr.Operations {
+		problems = append(problems, withOperation(validateUpOperation(operation), index, operation.ValidUpOperation())...)
+	}
+	return validationResult(problems)
+}
+
+func (opr *OperationsDownSerDer) Validate() error {
+	var problems []*util.MappingError
+	for index, operation := range opr.Operations {
+		problems = append(problems, withOperation(validateDownOperation(operation), index, operation.ValidDownOperation())...)
+	}
+	return validationResult(problems)
+}
+
+// validateUpOperation runs the validator of the operation handler, or checks its
+// expressions when the handler has none. Problems are returned with the operation
+// op set but not its index.
+func validateUpOperation(operation ontology.UpOperationInterface) []*util.MappingError {
+	var factory OperationFactory
+	var problems []*util.MappingError
+	handler, err := factory.BuildUp(operation)
+	if err != nil {
+		problems = []*util.MappingError{util.WithOperation(err, 0, operation.ValidUpOperation()).(*util.MappingError)}
+	} else if validator, ok := handler.(OperationValidator); ok {
+		problems = validator.ValidateUpOperation(operation)
+	} else {
+		problems = validateExpressions("", upOperationExpressions(handler, operation))
+	}
+	return withOperation(problems, 0, operation.ValidUpOperation())
+}
+
+func validateDownOperation(operation ontology.DownOperationInterface) []*util.MappingError {
+	var factory OperationFactory
+	var problems []*util.MappingError
+	handler, err := factory.BuildDown(operation)
+	if err != nil {
+		problems = []*util.MappingError{util.WithOperation(err, 0, operation.ValidDownOperation()).(*util.MappingError)}
+	} else if validator, ok := handler.(OperationValidator); ok {
+		problems = validator.ValidateDownOperation(operation)
+	} else {
+		problems = validateExpressions("", downOperationExpressions(handler, operation))
+	}
+	return withOperation(problems, 0, operation.ValidDownOperation())
+}
+
+func validateExpressions(key string, expressions []string) []*util.MappingError {
+	var problems []*util.MappingError
+	for _, expression := range expressions {
+		if err := util.ValidateExpression(expression); err != nil {
+			problems = append(problems, &util.MappingError{Code: util.INVALID_EXPRESSION_ErrorCode, Key: key, Expression: expression, Message: err.Error(), Err: err})
+		}
+	}
+	return problems
+}
+
+// withOperation records the operation on its problems, sorted by key so that the
+// report does not depend on map iteration order.
+func withOperation(problems []*util.MappingError, index int, op string) []*util.MappingError {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Key != problems[j].Key {
+			return problems[i].Key < problems[j].Key
+		}
+		return problems[i].Expression < problems[j].Expression
+	})
+	for _, problem := range problems {
+		problem.Index = index
+		problem.Op = op
+	}
+	return problems
+}
+
+func validationResult(problems []*util.MappingError) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+func validatePoint(key string, value string, eventTime string, coordinates []string, pointType ontology.JmesPathPointType) []*util.MappingError {
+	var problems = validateExpressions(key, append([]string{value, eventTime}, coordinates...))
+	if coordinates != nil && len(coordinates) != 2 && len(coordinates) != 3 {
+		problems = append(problems, &util.MappingError{Code: util.INVALID_COORDINATES_ErrorCode, Key: key,
+			Message: fmt.Sprintf("invalid 'coordinate' length %d, it must be 2 or 3", len(coordinates))})
+	}
+	if len(pointType) > 0 {
+		if _, err := pointType.FromValue(string(pointType)); err != nil {
+			problems = append(problems, &util.MappingError{Code: util.INVALID_POINT_TYPE_ErrorCode, Key: key,
+				Message: fmt.Sprintf("unknown point type '%s'", pointType)})
+		}
+	}
+	return problems
+}
+
+func validateCoordinateFormat(key string, point ontology.JmesPathPoint) []*util.MappingError {
+	var problems []*util.MappingError
+	switch point.CoordinateFormat {
+	case "", ontology.DECIMAL_CoordinateFormat, ontology.DMS_CoordinateFormat, ontology.NMEA_CoordinateFormat, ontology.SCALED_CoordinateFormat:
+	default:
+		problems = append(problems, &util.MappingError{Code: util.INVALID_COORDINATES_ErrorCode, Key: key,
+			Message: fmt.Sprintf("unknown 'coordinateFormat' '%s'", point.CoordinateFormat)})
+	}
+	switch point.CoordinateOrder {
+	case "", ontology.LNG_LAT_CoordinateOrder, ontology.LAT_LNG_CoordinateOrder:
+	default:
+		problems = append(problems, &util.MappingError{Code: util.INVALID_COORDINATES_ErrorCode, Key: key,
+			Message: fmt.Sprintf("unknown 'coordinateOrder' '%s', expected 'lngLat' or 'latLng'", point.CoordinateOrder)})
+	}
+	if point.CoordinateScale < 0 {
+		problems = append(problems, &util.MappingError{Code: util.INVALID_COORDINATES_ErrorCode, Key: key,
+			Message: fmt.Sprintf("'coordinateScale' %v must be positive", point.CoordinateScale)})
+	}
+	return problems
+}
+
+func validateEventTimeFormat(key string, format ontology.EventTimeFormat, timezone string) []*util.MappingError {
+	if err := util.ValidateEventTimeFormat(format, timezone); err != nil {
+		return []*util.MappingError{util.WithKey(err, key).(*util.MappingError)}
+	}
+	return nil
+}
+
+// validateSampling checks the sample period and direction of point, a period
+// given by an expression is only checked once evaluated.
+func validateSampling(key string, point ontology.JmesPathPoint) []*util.MappingError {
+	var problems []*util.MappingError
+	if util.IsJmesExpression(point.SamplePeriod) {
+		problems = validateExpressions(key, []string{point.SamplePeriod})
+	} else if len(point.SamplePeriod) > 0 {
+		if _, err := util.ParseSamplePeriod(point.SamplePeriod); err != nil {
+			problems = append(problems, &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Key: key,
+				Message: fmt.Sprintf("invalid 'samplePeriod': %v", err), Err: err})
+		}
+	}
+	switch point.SampleDirection {
+	case "":
+	case ontology.FORWARD_SampleDirection, ontology.BACKWARD_SampleDirection:
+		if len(point.SamplePeriod) == 0 {
+			problems = append(problems, &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Key: key, Message: "'sampleDirection' needs a 'samplePeriod'"})
+		}
+	default:
+		problems = append(problems, &util.MappingError{Code: util.INVALID_RULE_ErrorCode, Key: key,
+			Message: fmt.Sprintf("unknown 'sampleDirection' '%s', expected 'forward' or 'backward'", point.SampleDirection)})
+	}
+	return problems
+}
+
+// validateCommand checks that a command template is either an object, possibly
+// holding templates, or a single "{{ }}" template.
+func validateCommand(key string, command interface{}) []*util.MappingError {
+	var problems = validateExpressions(key, collectExpressions(command, nil))
+	if _, ok := command.(map[string]interface{}); ok {
+		return problems
+	}
+	if template, ok := command.(string); ok && util.IsJmesExpression(template) {
+		return problems
+	}
+	return append(problems, &util.MappingError{Code: util.INVALID_COMMAND_ErrorCode, Key: key, Message: "expected an object or a '{{ }}' template"})
+}
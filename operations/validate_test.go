@@ -0,0 +1,75 @@
+package operations
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"testing"
+)
+
+func Test_should_validate_valid_up_mapping(t *testing.T) {
+	// Given
+	operations := buildUpOperationsTemperature("{{packet.message.temperature}}")
+	// When
+	err := operations.Validate()
+	// Then
+	assert.Nil(t, err)
+}
+
+func Test_should_report_every_problem_of_up_mapping(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	_ = json.Unmarshal([]byte(`{"operations":[
+		{"op":"extractPoints","points":{
+			"temperature":{"value":"{{packet.message.[temperature}}","eventTime":"{{time}}","type":"float"},
+			"location":{"coordinates":["{{packet.message.lng}}"],"eventTime":"{{time}}"}}},
+		{"op":"filter","keepDeviceUplink":true},
+		{"op":"updatePoints","points":{}}]}`), &operations)
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.True(t, errors.Is(err, util.ErrInvalidExpression))
+	assert.False(t, errors.Is(err, util.ErrInvalidCommand))
+	assert.Equal(t, 4, len(validationErr.Problems))
+	assert.Equal(t, util.INVALID_COORDINATES_ErrorCode, validationErr.Problems[0].Code)
+	assert.Equal(t, "location", validationErr.Problems[0].Key)
+	assert.Equal(t, util.INVALID_POINT_TYPE_ErrorCode, validationErr.Problems[1].Code)
+	assert.Equal(t, "operation 0 'extractPoints', key 'temperature': unknown point type 'float'", validationErr.Problems[1].Error())
+	assert.Equal(t, util.INVALID_EXPRESSION_ErrorCode, validationErr.Problems[2].Code)
+	assert.Equal(t, "temperature", validationErr.Problems[2].Key)
+	assert.Equal(t, "{{packet.message.[temperature}}", validationErr.Problems[2].Expression)
+	assert.Equal(t, util.EMPTY_POINTS_ErrorCode, validationErr.Problems[3].Code)
+	assert.Equal(t, 2, validationErr.Problems[3].Index)
+}
+
+func Test_should_report_every_problem_of_down_mapping(t *testing.T) {
+	// Given
+	var extractOpr ontology.DownOperationInterface = ontology.DownExtractDriverMessage{Commands: map[string]interface{}{
+		"valid":    map[string]interface{}{"value": "{{command.input}}"},
+		"constant": "command.input",
+		"invalid":  map[string]interface{}{"value": "{{command.[input}}"},
+	}}
+	var updateOpr ontology.DownOperationInterface = ontology.DownUpdateCommand{Commands: map[string]ontology.UpdateCommand{
+		"constant": {Input: 10.0},
+		"template": {Input: "{{input.[prop1}}"},
+		"idOnly":   {Id: "newCommandId"},
+	}}
+	operations := OperationsDownSerDer{Operations: []ontology.DownOperationInterface{extractOpr, updateOpr}}
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 3, len(validationErr.Problems))
+	assert.Equal(t, util.INVALID_EXPRESSION_ErrorCode, validationErr.Problems[0].Code)
+	assert.Equal(t, "invalid", validationErr.Problems[0].Key)
+	assert.Equal(t, 0, validationErr.Problems[0].Index)
+	assert.Equal(t, "operation 1 'updateCommand', key 'constant': expected an object or a '{{ }}' template", validationErr.Problems[1].Error())
+	assert.Equal(t, util.INVALID_EXPRESSION_ErrorCode, validationErr.Problems[2].Code)
+	assert.Equal(t, "template", validationErr.Problems[2].Key)
+	assert.Equal(t, 1, validationErr.Problems[2].Index)
+}
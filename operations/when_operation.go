@@ -0,0 +1,297 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"reflect"
+)
+import "ontology-mapping-go-lib/models/ontology"
+import "ontology-mapping-go-lib/util"
+
+type WhenOperation struct {
+}
+
+func init() {
+	MustRegisterUpOperation("when", func() ontology.UpOperationInterface { return &ontology.UpWhen{} }, func() OperationHandler { return &WhenOperation{} })
+	MustRegisterDownOperation("when", func() ontology.DownOperationInterface { return &ontology.DownWhen{} }, func() OperationHandler { return &WhenOperation{} })
+}
+
+func (when *WhenOperation) ApplyUpOperation(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	return when.ApplyUpOperationContext(context.Background(), message, upOperation)
+}
+
+// ApplyUpOperationContext applies the Then or Else operations in turn, within the
+// time budget of the when operation itself.
+func (when *WhenOperation) ApplyUpOperationContext(ctx context.Context, message *flow.UpMessage, upOperation *ontology.UpOperationInterface) (*flow.UpMessage, error) {
+	var whenOperation = (*upOperation).(ontology.UpWhen)
+	holds, err := evaluatePredicate(ctx, whenOperation.Predicate, message)
+	if err != nil {
+		return nil, err
+	}
+	var branch, operations = "then", whenOperation.Then
+	if !holds {
+		branch, operations = "else", whenOperation.Else
+	}
+	var scope = operationScopeFrom(ctx)
+	var retMessage = message
+	for index, operation := range operations {
+		if retMessage == nil {
+			return nil, nil
+		}
+		handler, err := scope.factory.BuildUp(operation)
+		if err != nil {
+			return nil, nestedError(err, branch, index, operation.ValidUpOperation())
+		}
+		retMessage, err = applyUpOperation(ctx, 0, scope.observer, index, handler, retMessage, &operation)
+		if err != nil {
+			return nil, nestedError(err, branch, index, operation.ValidUpOperation())
+		}
+	}
+	return retMessage, nil
+}
+
+func (when *WhenOperation) ApplyDownOperation(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	return when.ApplyDownOperationContext(context.Background(), message, downOperation)
+}
+
+func (when *WhenOperation) ApplyDownOperationContext(ctx context.Context, message *flow.DownMessage, downOperation *ontology.DownOperationInterface) (*flow.DownMessage, error) {
+	var whenOperation = (*downOperation).(ontology.DownWhen)
+	holds, err := evaluatePredicate(ctx, whenOperation.Predicate, message)
+	if err != nil {
+		return nil, err
+	}
+	var branch, operations = "then", whenOperation.Then
+	if !holds {
+		branch, operations = "else", whenOperation.Else
+	}
+	var scope = operationScopeFrom(ctx)
+	var retMessage = message
+	for index, operation := range operations {
+		if retMessage == nil {
+			return nil, nil
+		}
+		handler, err := scope.factory.BuildDown(operation)
+		if err != nil {
+			return nil, nestedError(err, branch, index, operation.ValidDownOperation())
+		}
+		retMessage, err = applyDownOperation(ctx, 0, scope.observer, index, handler, retMessage, &operation)
+		if err != nil {
+			return nil, nestedError(err, branch, index, operation.ValidDownOperation())
+		}
+	}
+	return retMessage, nil
+}
+
+// ExplainUpDrop applies the branch again to find the nested operation that
+// dropped the message and gives its reason.
+func (when *WhenOperation) ExplainUpDrop(message *flow.UpMessage, upOperation *ontology.UpOperationInterface) string {
+	var whenOperation = (*upOperation).(ontology.UpWhen)
+	holds, err := evaluatePredicate(context.Background(), whenOperation.Predicate, message)
+	if err != nil {
+		return fmt.Sprintf("predicate '%s' failed: %v", whenOperation.Predicate, err)
+	}
+	var branch, operations = "then", whenOperation.Then
+	if !holds {
+		branch, operations = "else", whenOperation.Else
+	}
+	var factory OperationFactory
+	var retMessage = message
+	for index, operation := range operations {
+		handler, err := factory.BuildUp(operation)
+		if err != nil {
+			break
+		}
+		next, err := handler.ApplyUpOperation(retMessage, &operation)
+		if err != nil {
+			break
+		}
+		if next == nil {
+			return fmt.Sprintf("%s operation %d '%s': %s", branch, index, operation.ValidUpOperation(), explainUpDrop(handler, retMessage, &operation))
+		}
+		retMessage = next
+	}
+	return "the operation returned no message"
+}
+
+func (when *WhenOperation) ExplainDownDrop(message *flow.DownMessage, downOperation *ontology.DownOperationInterface) string {
+	var whenOperation = (*downOperation).(ontology.DownWhen)
+	holds, err := evaluatePredicate(context.Background(), whenOperation.Predicate, message)
+	if err != nil {
+		return fmt.Sprintf("predicate '%s' failed: %v", whenOperation.Predicate, err)
+	}
+	var branch, operations = "then", whenOperation.Then
+	if !holds {
+		branch, operations = "else", whenOperation.Else
+	}
+	var factory OperationFactory
+	var retMessage = message
+	for index, operation := range operations {
+		handler, err := factory.BuildDown(operation)
+		if err != nil {
+			break
+		}
+		next, err := handler.ApplyDownOperation(retMessage, &operation)
+		if err != nil {
+			break
+		}
+		if next == nil {
+			return fmt.Sprintf("%s operation %d '%s': %s", branch, index, operation.ValidDownOperation(), explainDownDrop(handler, retMessage, &operation))
+		}
+		retMessage = next
+	}
+	return "the operation returned no message"
+}
+
+func (when *WhenOperation) UpExpressions(upOperation ontology.UpOperationInterface) []string {
+	var whenOperation = upOperation.(ontology.UpWhen)
+	var expressions = []string{whenOperation.Predicate}
+	var factory OperationFactory
+	for _, operation := range append(append([]ontology.UpOperationInterface(nil), whenOperation.Then...), whenOperation.Else...) {
+		if handler, err := factory.BuildUp(operation); err == nil {
+			expressions = append(expressions, upOperationExpressions(handler, operation)...)
+		}
+	}
+	return expressions
+}
+
+func (when *WhenOperation) DownExpressions(downOperation ontology.DownOperationInterface) []string {
+	var whenOperation = downOperation.(ontology.DownWhen)
+	var expressions = []string{whenOperation.Predicate}
+	var factory OperationFactory
+	for _, operation := range append(append([]ontology.DownOperationInterface(nil), whenOperation.Then...), whenOperation.Else...) {
+		if handler, err := factory.BuildDown(operation); err == nil {
+			expressions = append(expressions, downOperationExpressions(handler, operation)...)
+		}
+	}
+	return expressions
+}
+
+// ValidateUpOperation validates the nested operations as well, their problems are
+// keyed by their position such as "then[1]" or "else[0].temperature".
+func (when *WhenOperation) ValidateUpOperation(upOperation ontology.UpOperationInterface) []*util.MappingError {
+	var whenOperation = upOperation.(ontology.UpWhen)
+	var problems = validatePredicate("predicate", whenOperation.Predicate)
+	for index, operation := range whenOperation.Then {
+		problems = append(problems, nestedProblems(validateUpOperation(operation), "then", index)...)
+	}
+	for index, operation := range whenOperation.Else {
+		problems = append(problems, nestedProblems(validateUpOperation(operation), "else", index)...)
+	}
+	return problems
+}
+
+func (when *WhenOperation) ValidateDownOperation(downOperation ontology.DownOperationInterface) []*util.MappingError {
+	var whenOperation = downOperation.(ontology.DownWhen)
+	var problems = validatePredicate("predicate", whenOperation.Predicate)
+	for index, operation := range whenOperation.Then {
+		problems = append(problems, nestedProblems(validateDownOperation(operation), "then", index)...)
+	}
+	for index, operation := range whenOperation.Else {
+		problems = append(problems, nestedProblems(validateDownOperation(operation), "else", index)...)
+	}
+	return problems
+}
+
+// validatePredicate checks that the predicate held by field is a valid template.
+func validatePredicate(field string, predicate string) []*util.MappingError {
+	if !util.IsJmesExpression(predicate) {
+		return []*util.MappingError{{Code: util.INVALID_EXPRESSION_ErrorCode, Expression: predicate, Message: fmt.Sprintf("'%s' must be a '{{ }}' template", field)}}
+	}
+	return validateExpressions("", []string{predicate})
+}
+
+// evaluatePredicate follows the JMESPath notion of truth: false, null, empty
+// strings, arrays and objects are false, any other value is true. The message is
+// searched in its JSON form as JMESPath functions reject typed slices such as
+// Thing.Tags.
+func evaluatePredicate(ctx context.Context, predicate string, message interface{}) (bool, error) {
+	messageJson, err := jsonView(ctx, message)
+	if err != nil {
+		return false, err
+	}
+	result, err := util.RetrieveValuesContext(ctx, predicate, &messageJson)
+	if err != nil {
+		return false, err
+	}
+	switch value := result.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return value, nil
+	case string:
+		return len(value) > 0, nil
+	}
+	var reflected = reflect.ValueOf(result)
+	switch reflected.Kind() {
+	case reflect.Slice, reflect.Map:
+		return reflected.Len() > 0, nil
+	}
+	return true, nil
+}
+
+// nestedError reports the failure of a nested operation as a failure of the when
+// operation, its message naming the nested operation. Deadline and cancellation
+// errors are wrapped so that errors.Is still finds them.
+func nestedError(err error, branch string, index int, op string) error {
+	if _, ok := err.(*OperationDeadlineError); ok {
+		// the deadline error names the nested operation already
+		return fmt.Errorf("%s %w", branch, err)
+	}
+	var mappingError *util.MappingError
+	if !errors.As(err, &mappingError) {
+		return fmt.Errorf("%s operation %d '%s': %w", branch, index, op, err)
+	}
+	var nested = *mappingError
+	nested.Index, nested.Op = 0, ""
+	nested.Message = fmt.Sprintf("%s operation %d '%s': %s", branch, index, op, mappingError.Message)
+	return &nested
+}
+
+func nestedProblems(problems []*util.MappingError, branch string, index int) []*util.MappingError {
+	for _, problem := range problems {
+		var key = fmt.Sprintf("%s[%d]", branch, index)
+		if len(problem.Key) > 0 {
+			key += "." + problem.Key
+		}
+		problem.Message = fmt.Sprintf("'%s': %s", problem.Op, problem.Message)
+		problem.Key, problem.Index, problem.Op = key, 0, ""
+	}
+	return problems
+}
+
+// decodeUpOperations decodes the operations nested in a model, recursively
+// since they may nest operations themselves.
+func decodeUpOperations(branch string, raws []json.RawMessage) ([]ontology.UpOperationInterface, error) {
+	var operations []ontology.UpOperationInterface
+	for index, raw := range raws {
+		var operation ontology.UpOperation
+		if err := json.Unmarshal(raw, &operation); err != nil {
+			return nil, nestedError(err, branch, index, "")
+		}
+		decoded, err := decodeUpOperation(index, operation.Op, raw)
+		if err != nil {
+			return nil, nestedError(err, branch, index, operation.Op)
+		}
+		operations = append(operations, decoded)
+	}
+	return operations, nil
+}
+
+func decodeDownOperations(branch string, raws []json.RawMessage) ([]ontology.DownOperationInterface, error) {
+	var operations []ontology.DownOperationInterface
+	for index, raw := range raws {
+		var operation ontology.DownOperation
+		if err := json.Unmarshal(raw, &operation); err != nil {
+			return nil, nestedError(err, branch, index, "")
+		}
+		decoded, err := decodeDownOperation(index, operation.Op, raw)
+		if err != nil {
+			return nil, nestedError(err, branch, index, operation.Op)
+		}
+		operations = append(operations, decoded)
+	}
+	return operations, nil
+}
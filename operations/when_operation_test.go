@@ -0,0 +1,236 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"ontology-mapping-go-lib/models/ontology"
+	"ontology-mapping-go-lib/util"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var whenOperation = WhenOperation{}
+
+const whenMapping = `{"operations":[
+	{"op":"when","predicate":"{{packet.message.temperature > ` + "`20`" + `}}",
+		"then":[{"op":"extractPoints","points":{"hot":{"value":"{{packet.message.temperature}}","eventTime":"{{time}}","type":"double"}}}],
+		"else":[{"op":"when","predicate":"{{packet.message.temperature}}",
+			"then":[{"op":"extractPoints","points":{"cold":{"value":"{{packet.message.temperature}}","eventTime":"{{time}}","type":"double"}}}]}]}]}`
+
+func Test_should_decode_when_operations_recursively(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	// When
+	err := json.Unmarshal([]byte(whenMapping), &operations)
+	// Then
+	assert.Nil(t, err)
+	var when = operations.Operations[0].(ontology.UpWhen)
+	assert.IsType(t, ontology.UpExtractPoints{}, when.Then[0])
+	var nested = when.Else[0].(ontology.UpWhen)
+	assert.Equal(t, "{{packet.message.temperature}}", nested.Predicate)
+	assert.IsType(t, ontology.UpExtractPoints{}, nested.Then[0])
+	assert.Nil(t, nested.Else)
+}
+
+func Test_should_marshal_nested_when_operations(t *testing.T) {
+	// Given
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"hot": {Value: "{{packet.message.temperature}}", EventTime: "{{time}}"},
+	}, UpOperation: ontology.UpOperation{Op: "extractPoints"}}
+	var whenOpr ontology.UpOperationInterface = ontology.UpWhen{Predicate: "{{packet.message.temperature}}", Then: []ontology.UpOperationInterface{extractOpr},
+		UpOperation: ontology.UpOperation{Op: "when"}}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{whenOpr}}
+	// When
+	raw, err := json.Marshal(&operations)
+	var decoded OperationsUpSerDer
+	_ = json.Unmarshal(raw, &decoded)
+	// Then
+	assert.Nil(t, err)
+	var when = decoded.Operations[0].(ontology.UpWhen)
+	assert.Equal(t, "{{packet.message.temperature}}", when.Then[0].(ontology.UpExtractPoints).Points["hot"].Value)
+}
+
+func Test_should_apply_then_or_else_operations_depending_on_predicate(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	_ = json.Unmarshal([]byte(whenMapping), &operations)
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	var coldUpMessage = buildInputUpMessage("include_existing_points.json")
+	coldUpMessage.Packet.Message = map[string]interface{}{"temperature": 12.5}
+	// When
+	hotUpMessage, hotErr := whenOperation.ApplyUpOperation(&inputUpMessage, &operations.Operations[0])
+	outputUpMessage, coldErr := whenOperation.ApplyUpOperation(&coldUpMessage, &operations.Operations[0])
+	// Then
+	assert.Nil(t, hotErr)
+	assert.Nil(t, coldErr)
+	assert.Equal(t, []interface{}{22.6}, recordValues(hotUpMessage.Points["hot"]))
+	assert.NotContains(t, hotUpMessage.Points, "cold")
+	assert.Equal(t, []interface{}{12.5}, recordValues(outputUpMessage.Points["cold"]))
+	assert.NotContains(t, outputUpMessage.Points, "hot")
+}
+
+func Test_should_leave_message_unchanged_when_predicate_is_null_without_else(t *testing.T) {
+	// Given
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"humidity": {Value: "{{packet.message.humidity}}", EventTime: "{{time}}"},
+	}}
+	var whenOpr ontology.UpOperationInterface = ontology.UpWhen{Predicate: "{{packet.message.humidity}}", Then: []ontology.UpOperationInterface{extractOpr}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	outputUpMessage, err := whenOperation.ApplyUpOperation(&inputUpMessage, &whenOpr)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, &inputUpMessage, outputUpMessage)
+}
+
+func Test_should_name_failing_nested_operation(t *testing.T) {
+	// Given
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"temperature": {Value: "{{packet.message.[temperature}}", EventTime: "{{time}}"},
+	}}
+	var whenOpr ontology.UpOperationInterface = ontology.UpWhen{Predicate: "{{packet.message.temperature}}", Then: []ontology.UpOperationInterface{extractOpr}}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{whenOpr}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	_, err := pipelineService.ApplyUpOperations(&inputUpMessage, &operations)
+	// Then
+	var mappingErr *util.MappingError
+	assert.True(t, errors.As(err, &mappingErr))
+	assert.Equal(t, util.INVALID_EXPRESSION_ErrorCode, mappingErr.Code)
+	assert.Equal(t, "when", mappingErr.Op)
+	assert.Equal(t, "temperature", mappingErr.Key)
+	assert.Contains(t, mappingErr.Error(), "then operation 0 'extractPoints'")
+}
+
+func Test_should_observe_nested_operations(t *testing.T) {
+	// Given
+	observer := NewCounterObserver()
+	service := OperationService{Factory: OperationFactory{}, Observer: observer}
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceLocation: true}
+	var whenOpr ontology.UpOperationInterface = ontology.UpWhen{Predicate: "{{time}}", Then: []ontology.UpOperationInterface{filterOpr}}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{whenOpr}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	pipeline, _ := service.CompileUpOperations(&operations)
+	// When
+	outputUpMessage, err := service.ApplyUpOperations(&inputUpMessage, &operations)
+	_, _ = pipeline.Apply(&inputUpMessage)
+	// Then
+	assert.Nil(t, err)
+	assert.Nil(t, outputUpMessage)
+	assert.Equal(t, 2.0, observer.Counter(OperationsStartedMetric, upLabels("", "filter")))
+	assert.Equal(t, 2.0, observer.Counter(MessagesDroppedMetric, upLabels("", "filter")))
+	assert.Equal(t, 0.0, observer.Counter(MessagesDroppedMetric, upLabels("", "when")))
+}
+
+func Test_should_count_failure_of_nested_operation_once(t *testing.T) {
+	// Given
+	observer := NewCounterObserver()
+	service := OperationService{Factory: OperationFactory{}, Observer: observer}
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"temperature": {Value: "{{packet.message.[temperature}}", EventTime: "{{time}}"},
+	}}
+	var whenOpr ontology.UpOperationInterface = ontology.UpWhen{Predicate: "{{time}}", Then: []ontology.UpOperationInterface{extractOpr}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	_, err := service.ApplyUpOperations(&inputUpMessage, &OperationsUpSerDer{Operations: []ontology.UpOperationInterface{whenOpr}})
+	// Then
+	assert.NotNil(t, err)
+	var extractLabels = upLabels("", "extractPoints")
+	extractLabels["code"] = string(util.INVALID_EXPRESSION_ErrorCode)
+	var whenLabels = upLabels("", "when")
+	whenLabels["code"] = string(util.INVALID_EXPRESSION_ErrorCode)
+	assert.Equal(t, 1.0, observer.Counter(OperationErrorsMetric, extractLabels))
+	assert.Equal(t, 0.0, observer.Counter(OperationErrorsMetric, whenLabels))
+	assert.Equal(t, 1.0, observer.Counter(OperationsEndedMetric, upLabels("", "when")))
+}
+
+func Test_should_explain_drop_by_nested_operation(t *testing.T) {
+	// Given
+	var filterOpr ontology.UpOperationInterface = ontology.UpFilterOperation{KeepDeviceLocation: true}
+	var whenOpr ontology.UpOperationInterface = ontology.UpWhen{Predicate: "{{time}}", Then: []ontology.UpOperationInterface{filterOpr}}
+	var operations = OperationsUpSerDer{Operations: []ontology.UpOperationInterface{whenOpr}}
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	explanation, err := pipelineService.ExplainUpOperations(&inputUpMessage, &operations)
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "then operation 0 'filter': message type 'deviceUplink' is not kept, 'keepDeviceUplink' is false", explanation.Operations[0].DropReason)
+}
+
+func Test_should_build_json_view_of_message_once_per_apply(t *testing.T) {
+	// Given
+	var scope = withOperationScope(context.Background(), OperationFactory{}, nil)
+	inputUpMessage := buildInputUpMessage("include_existing_points.json")
+	// When
+	first, firstErr := jsonView(scope, &inputUpMessage)
+	second, secondErr := jsonView(scope, &inputUpMessage)
+	holds, predicateErr := evaluatePredicate(scope, "{{type == 'deviceUplink'}}", &inputUpMessage)
+	// Then
+	assert.Nil(t, firstErr)
+	assert.Nil(t, secondErr)
+	assert.Nil(t, predicateErr)
+	assert.True(t, holds)
+	assert.Equal(t, reflect.ValueOf(first).Pointer(), reflect.ValueOf(second).Pointer())
+}
+
+func Test_should_name_nested_operation_that_exceeded_the_deadline(t *testing.T) {
+	// Given
+	var deadlineErr = operationError(context.DeadlineExceeded, 1, "extractPoints", 0)
+	// When
+	err := operationError(nestedError(deadlineErr, "then", 1, "extractPoints"), 0, "when", time.Second)
+	canceledErr := nestedError(operationError(context.Canceled, 0, "filter", 0), "else", 0, "filter")
+	// Then
+	var nestedDeadlineErr *OperationDeadlineError
+	assert.True(t, errors.As(errors.Unwrap(err), &nestedDeadlineErr))
+	assert.Equal(t, "extractPoints", nestedDeadlineErr.Op)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, "operation 0 'when' exceeded its time budget of 1s: then operation 1 'extractPoints' exceeded the deadline: context deadline exceeded", err.Error())
+	assert.True(t, errors.Is(canceledErr, context.Canceled))
+	assert.Equal(t, "else operation 0 'filter': context canceled", canceledErr.Error())
+}
+
+func Test_should_reject_unknown_nested_operation(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	// When
+	err := json.Unmarshal([]byte(`{"operations":[{"op":"when","predicate":"{{time}}","else":[{"op":"filter"},{"op":"unknown"}]}]}`), &operations)
+	// Then
+	assert.True(t, errors.Is(err, util.ErrUnknownOperation))
+	assert.Equal(t, "operation 0 'when': else operation 1 'unknown': unknown operation type", err.Error())
+}
+
+func Test_should_report_problems_of_nested_operations(t *testing.T) {
+	// Given
+	var operations OperationsUpSerDer
+	_ = json.Unmarshal([]byte(`{"operations":[
+		{"op":"filter","keepDeviceUplink":true},
+		{"op":"when","predicate":"packet.message.temperature",
+			"then":[{"op":"extractPoints","points":{"temperature":{"value":"{{packet.message.[temperature}}","eventTime":"{{time}}"}}}]}]}`), &operations)
+	// When
+	err := operations.Validate()
+	// Then
+	var validationErr *ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, len(validationErr.Problems))
+	assert.Equal(t, "packet.message.temperature", validationErr.Problems[0].Expression)
+	assert.Equal(t, 1, validationErr.Problems[0].Index)
+	assert.Equal(t, "then[0].temperature", validationErr.Problems[1].Key)
+	assert.Equal(t, "when", validationErr.Problems[1].Op)
+}
+
+func Test_should_apply_down_when_operation(t *testing.T) {
+	// Given
+	var operations OperationsDownSerDer
+	_ = json.Unmarshal([]byte(`{"operations":[{"op":"when","predicate":"{{command.id == 'myDeviceCommand'}}",
+		"then":[{"op":"updateCommand","commands":{"myDeviceCommand":{"id":"newCommandId"}}}]}]}`), &operations)
+	inputDownMessage := buildInputDownMessage("update_command_id.json")
+	// When
+	outputDownMessage, err := whenOperation.ApplyDownOperation(&inputDownMessage, &operations.Operations[0])
+	// Then
+	assert.Nil(t, err)
+	assert.Equal(t, "newCommandId", outputDownMessage.Command.Id)
+	assert.Equal(t, "myDeviceCommand", inputDownMessage.Command.Id)
+}
@@ -617,3 +617,37 @@ func Test_should_update_commands(t *testing.T) {
 	expectedOutputMessage.Command.Input = data.(map[string]interface{})["input"]
 	assert.Equal(t, outputMessage, expectedOutputMessage)
 }
+
+func Test_should_aggregate_sensing_labs_temperature_samples(t *testing.T) {
+	// Given
+	inputUpMessage := buildInputUpMessage("sensing_labs.json", map[string]flow.Point{})
+	var operation operations.OperationsUpSerDer
+	var extractOpr ontology.UpOperationInterface = ontology.UpExtractPoints{Points: map[string]ontology.JmesPathPoint{
+		"temperature": {
+			Value:     "{{packet.message.measures[?id == 'temperature'].value}}",
+			EventTime: "{{packet.message.measures[?id == 'temperature'].time}}",
+			Type_:     "double",
+			UnitId:    "Cel",
+		},
+	}}
+	var aggregateOpr ontology.UpOperationInterface = ontology.UpAggregatePoints{Points: map[string]ontology.AggregatePoint{
+		"temperatureMax": {Source: "temperature", Function: "max", OntologyId: "Temperature:max"},
+		"temperatureMin": {Source: "temperature", Function: "min", EventTime: "first"},
+	}}
+	operation.Operations = append(operation.Operations, extractOpr, aggregateOpr)
+	// When
+	outputUpMessage, err := oprServ.ApplyUpOperations(&inputUpMessage, &operation)
+	// Then
+	assert.Nil(t, err)
+	resTime1, _ := time.Parse(time.RFC3339, "2020-02-06T09:14:05.688Z")
+	resTime3, _ := time.Parse(time.RFC3339, "2020-02-06T09:17:25.688Z")
+	assert.Equal(t, flow.Point{OntologyId: "Temperature:max", Type_: "double", UnitId: "Cel", Records: []flow.Record{{
+		Value:     11.6875,
+		EventTime: resTime3,
+	}}}, outputUpMessage.Points["temperatureMax"])
+	assert.Equal(t, flow.Point{Type_: "double", UnitId: "Cel", Records: []flow.Record{{
+		Value:     11.625,
+		EventTime: resTime1,
+	}}}, outputUpMessage.Points["temperatureMin"])
+	assert.Equal(t, 3, len(outputUpMessage.Points["temperature"].Records))
+}
@@ -0,0 +1,275 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ArithmeticExpression is a compiled expression such as
+// "temperature - (100 - humidity) / 5" where names are point keys. It supports
+// + - * / % ^, parentheses and the functions abs, sqrt, exp, ln, log10, pow, min,
+// max, round, floor and ceil. Names holding other characters than letters,
+// digits, '_' and '.' are quoted with backticks.
+type ArithmeticExpression struct {
+	source    string
+	root      arithmeticNode
+	variables map[string]bool
+}
+
+type arithmeticNode func(variables map[string]float64) float64
+
+type arithmeticFunction struct {
+	arity    int // -1 for one or more arguments
+	evaluate func(arguments []float64) float64
+}
+
+var arithmeticFunctions = map[string]arithmeticFunction{
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"exp":   {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"ln":    {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"log10": {1, func(a []float64) float64 { return math.Log10(a[0]) }},
+	"round": {1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"min": {-1, func(a []float64) float64 {
+		var min = a[0]
+		for _, value := range a[1:] {
+			min = math.Min(min, value)
+		}
+		return min
+	}},
+	"max": {-1, func(a []float64) float64 {
+		var max = a[0]
+		for _, value := range a[1:] {
+			max = math.Max(max, value)
+		}
+		return max
+	}},
+}
+
+func CompileArithmetic(expression string) (*ArithmeticExpression, error) {
+	var parser = arithmeticParser{input: expression, variables: make(map[string]bool)}
+	root, err := parser.parseSum()
+	if err == nil && parser.skipSpaces() < len(parser.input) {
+		err = parser.errorf("unexpected '%c'", parser.input[parser.position])
+	}
+	if err != nil {
+		return nil, &MappingError{Code: INVALID_EXPRESSION_ErrorCode, Expression: expression, Message: err.Error(), Err: err}
+	}
+	return &ArithmeticExpression{source: expression, root: root, variables: parser.variables}, nil
+}
+
+// Variables returns the sorted names used by the expression.
+func (expression *ArithmeticExpression) Variables() []string {
+	var names []string
+	for name := range expression.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Evaluate computes the expression, every name of Variables must be given a value.
+func (expression *ArithmeticExpression) Evaluate(variables map[string]float64) (float64, error) {
+	for name := range expression.variables {
+		if _, ok := variables[name]; !ok {
+			return 0, &MappingError{Code: UNEXPECTED_RESULT_ErrorCode, Expression: expression.source, Message: fmt.Sprintf("no value for '%s'", name)}
+		}
+	}
+	var result = expression.root(variables)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, &MappingError{Code: UNEXPECTED_RESULT_ErrorCode, Expression: expression.source, Message: fmt.Sprintf("the result %v is not a finite number", result)}
+	}
+	return result, nil
+}
+
+type arithmeticParser struct {
+	input     string
+	position  int
+	variables map[string]bool
+}
+
+func (parser *arithmeticParser) errorf(format string, arguments ...interface{}) error {
+	return fmt.Errorf("at %d: %s", parser.position, fmt.Sprintf(format, arguments...))
+}
+
+func (parser *arithmeticParser) skipSpaces() int {
+	for parser.position < len(parser.input) && unicode.IsSpace(rune(parser.input[parser.position])) {
+		parser.position++
+	}
+	return parser.position
+}
+
+// accept consumes operator when it is the next non space character.
+func (parser *arithmeticParser) accept(operator byte) bool {
+	if parser.skipSpaces() < len(parser.input) && parser.input[parser.position] == operator {
+		parser.position++
+		return true
+	}
+	return false
+}
+
+func (parser *arithmeticParser) parseSum() (arithmeticNode, error) {
+	left, err := parser.parseProduct()
+	for err == nil {
+		var operand = left
+		var right arithmeticNode
+		switch {
+		case parser.accept('+'):
+			if right, err = parser.parseProduct(); err == nil {
+				left = func(v map[string]float64) float64 { return operand(v) + right(v) }
+			}
+		case parser.accept('-'):
+			if right, err = parser.parseProduct(); err == nil {
+				left = func(v map[string]float64) float64 { return operand(v) - right(v) }
+			}
+		default:
+			return left, nil
+		}
+	}
+	return nil, err
+}
+
+func (parser *arithmeticParser) parseProduct() (arithmeticNode, error) {
+	left, err := parser.parseUnary()
+	for err == nil {
+		var operand = left
+		var right arithmeticNode
+		switch {
+		case parser.accept('*'):
+			if right, err = parser.parseUnary(); err == nil {
+				left = func(v map[string]float64) float64 { return operand(v) * right(v) }
+			}
+		case parser.accept('/'):
+			if right, err = parser.parseUnary(); err == nil {
+				left = func(v map[string]float64) float64 { return operand(v) / right(v) }
+			}
+		case parser.accept('%'):
+			if right, err = parser.parseUnary(); err == nil {
+				left = func(v map[string]float64) float64 { return math.Mod(operand(v), right(v)) }
+			}
+		default:
+			return left, nil
+		}
+	}
+	return nil, err
+}
+
+func (parser *arithmeticParser) parseUnary() (arithmeticNode, error) {
+	if parser.accept('-') {
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(v map[string]float64) float64 { return -operand(v) }, nil
+	}
+	base, err := parser.parsePrimary()
+	if err != nil || !parser.accept('^') {
+		return base, err
+	}
+	// ^ is right associative and binds tighter than a unary minus on its left
+	exponent, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return func(v map[string]float64) float64 { return math.Pow(base(v), exponent(v)) }, nil
+}
+
+func (parser *arithmeticParser) parsePrimary() (arithmeticNode, error) {
+	if parser.skipSpaces() >= len(parser.input) {
+		return nil, parser.errorf("unexpected end of expression")
+	}
+	var next = parser.input[parser.position]
+	switch {
+	case parser.accept('('):
+		node, err := parser.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.accept(')') {
+			return nil, parser.errorf("missing ')'")
+		}
+		return node, nil
+	case next == '`':
+		var end = strings.IndexByte(parser.input[parser.position+1:], '`')
+		if end < 0 {
+			return nil, parser.errorf("missing closing '`'")
+		}
+		var name = parser.input[parser.position+1 : parser.position+1+end]
+		parser.position += end + 2
+		return parser.variable(name), nil
+	case next >= '0' && next <= '9' || next == '.':
+		var start = parser.position
+		for parser.position < len(parser.input) && strings.IndexByte("0123456789.eE", parser.input[parser.position]) >= 0 {
+			parser.position++
+			var exponent = parser.input[parser.position-1] == 'e' || parser.input[parser.position-1] == 'E'
+			if exponent && parser.position < len(parser.input) && strings.IndexByte("+-", parser.input[parser.position]) >= 0 {
+				parser.position++
+			}
+		}
+		number, err := strconv.ParseFloat(parser.input[start:parser.position], 64)
+		if err != nil {
+			return nil, parser.errorf("invalid number '%s'", parser.input[start:parser.position])
+		}
+		return func(map[string]float64) float64 { return number }, nil
+	case next == '_' || unicode.IsLetter(rune(next)):
+		var start = parser.position
+		for parser.position < len(parser.input) && isNameCharacter(parser.input[parser.position]) {
+			parser.position++
+		}
+		var name = parser.input[start:parser.position]
+		if parser.accept('(') {
+			return parser.parseCall(name)
+		}
+		return parser.variable(name), nil
+	default:
+		return nil, parser.errorf("unexpected '%c'", next)
+	}
+}
+
+func (parser *arithmeticParser) parseCall(name string) (arithmeticNode, error) {
+	function, ok := arithmeticFunctions[name]
+	if !ok {
+		return nil, parser.errorf("unknown function '%s'", name)
+	}
+	var arguments []arithmeticNode
+	for !parser.accept(')') {
+		if len(arguments) > 0 && !parser.accept(',') {
+			return nil, parser.errorf("expected ',' or ')' in '%s' call", name)
+		}
+		argument, err := parser.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+	if function.arity < 0 && len(arguments) == 0 {
+		return nil, parser.errorf("'%s' takes at least 1 argument", name)
+	}
+	if function.arity >= 0 && len(arguments) != function.arity {
+		return nil, parser.errorf("'%s' takes %d argument(s), got %d", name, function.arity, len(arguments))
+	}
+	return func(v map[string]float64) float64 {
+		var values = make([]float64, len(arguments))
+		for i, argument := range arguments {
+			values[i] = argument(v)
+		}
+		return function.evaluate(values)
+	}, nil
+}
+
+func (parser *arithmeticParser) variable(name string) arithmeticNode {
+	parser.variables[name] = true
+	return func(v map[string]float64) float64 { return v[name] }
+}
+
+func isNameCharacter(character byte) bool {
+	return character == '_' || character == '.' || character >= '0' && character <= '9' ||
+		character >= 'a' && character <= 'z' || character >= 'A' && character <= 'Z'
+}
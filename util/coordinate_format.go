@@ -0,0 +1,159 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"ontology-mapping-go-lib/models/ontology"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CoordinateAxis is the axis a coordinate is read for, it bounds its range and
+// the hemispheres it may be given in.
+type CoordinateAxis string
+
+const (
+	LONGITUDE_CoordinateAxis CoordinateAxis = "longitude"
+	LATITUDE_CoordinateAxis  CoordinateAxis = "latitude"
+)
+
+// defaultCoordinateScale reads scaled coordinates as microdegrees.
+const defaultCoordinateScale = 1000000
+
+var (
+	dmsPattern  = regexp.MustCompile(`^([NSEW])?\s*([+-])?(\d+(?:\.\d+)?)\s*(?:°|º|:)?\s*(?:(\d+(?:\.\d+)?)\s*(?:'|′|:)?\s*)?(?:(\d+(?:\.\d+)?)\s*(?:"|″|'')?\s*)?([NSEW])?$`)
+	nmeaPattern = regexp.MustCompile(`^([+-]?\d+(?:\.\d+)?)\s*,?\s*([NSEW])?$`)
+)
+
+// ParseCoordinate reads a longitude or a latitude written in format and checks it
+// lies in the range of its axis. scale is only used by the scaled format.
+func ParseCoordinate(value interface{}, format ontology.CoordinateFormat, scale float64, axis CoordinateAxis) (float64, error) {
+	if value == nil {
+		return 0, NewMappingError(INVALID_COORDINATES_ErrorCode, fmt.Sprintf("missing %s", axis))
+	}
+	var degrees float64
+	var err error
+	switch format {
+	case "", ontology.DECIMAL_CoordinateFormat:
+		degrees, err = toDouble(value)
+	case ontology.SCALED_CoordinateFormat:
+		degrees, err = parseScaled(value, scale)
+	case ontology.DMS_CoordinateFormat:
+		degrees, err = parseDms(value, axis)
+	case ontology.NMEA_CoordinateFormat:
+		degrees, err = parseNmea(value, axis)
+	default:
+		return 0, NewMappingError(INVALID_COORDINATES_ErrorCode, fmt.Sprintf("unknown coordinate format '%s'", format))
+	}
+	if err != nil {
+		return 0, err
+	}
+	var bound = 180.0
+	if axis == LATITUDE_CoordinateAxis {
+		bound = 90
+	}
+	if math.IsNaN(degrees) || degrees < -bound || degrees > bound {
+		return 0, NewMappingError(INVALID_COORDINATES_ErrorCode, fmt.Sprintf("%s %v is out of range [-%v, %v]", axis, degrees, bound, bound))
+	}
+	return degrees, nil
+}
+
+func invalidCoordinate(value interface{}, format ontology.CoordinateFormat, axis CoordinateAxis, reason string) error {
+	return NewMappingError(INVALID_COORDINATES_ErrorCode, fmt.Sprintf("invalid %s %s %v: %s", format, axis, value, reason))
+}
+
+func parseScaled(value interface{}, scale float64) (float64, error) {
+	if scale == 0 {
+		scale = defaultCoordinateScale
+	}
+	var units float64
+	switch typed := value.(type) {
+	case float64:
+		units = typed
+	case json.Number:
+		return parseScaled(typed.String(), scale)
+	case string:
+		integer, err := strconv.ParseInt(strings.TrimSpace(typed), 10, 64)
+		if err != nil {
+			return 0, NewMappingError(INVALID_COORDINATES_ErrorCode, fmt.Sprintf("invalid scaled coordinate '%s': not an integer", typed))
+		}
+		units = float64(integer)
+	default:
+		return 0, NewMappingError(INVALID_COORDINATES_ErrorCode, fmt.Sprintf("invalid scaled coordinate %v: not an integer", value))
+	}
+	return units / scale, nil
+}
+
+// parseDms reads 43°39'42.7"N, 43 39 42.7 N, N43°39.712', -43:39:42.7 and the
+// like, minutes and seconds being optional.
+func parseDms(value interface{}, axis CoordinateAxis) (float64, error) {
+	text, ok := value.(string)
+	if !ok {
+		return toDouble(value)
+	}
+	var parts = dmsPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(text)))
+	if parts == nil {
+		return 0, invalidCoordinate(text, ontology.DMS_CoordinateFormat, axis, "expected degrees, minutes and seconds")
+	}
+	var hemisphere, sign = parts[1] + parts[6], parts[2]
+	if len(parts[1]) > 0 && len(parts[6]) > 0 {
+		return 0, invalidCoordinate(text, ontology.DMS_CoordinateFormat, axis, "hemisphere given twice")
+	}
+	var components [3]float64
+	for i, part := range parts[3:6] {
+		if len(part) > 0 {
+			components[i], _ = strconv.ParseFloat(part, 64)
+		}
+	}
+	switch {
+	case len(parts[4]) > 0 && strings.Contains(parts[3], "."):
+		return 0, invalidCoordinate(text, ontology.DMS_CoordinateFormat, axis, "fractional degrees followed by minutes")
+	case len(parts[5]) > 0 && strings.Contains(parts[4], "."):
+		return 0, invalidCoordinate(text, ontology.DMS_CoordinateFormat, axis, "fractional minutes followed by seconds")
+	case components[1] >= 60 || components[2] >= 60:
+		return 0, invalidCoordinate(text, ontology.DMS_CoordinateFormat, axis, "minutes and seconds must be less than 60")
+	}
+	var degrees = components[0] + components[1]/60 + components[2]/3600
+	return applyHemisphere(text, degrees, sign, hemisphere, ontology.DMS_CoordinateFormat, axis)
+}
+
+// parseNmea reads the [d]ddmm.mmmm values of NMEA 0183 sentences, given as a
+// number or as a string possibly followed by the hemisphere such as "00703.52,E".
+func parseNmea(value interface{}, axis CoordinateAxis) (float64, error) {
+	var text = fmt.Sprint(value)
+	if number, ok := value.(float64); ok {
+		text = strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	var parts = nmeaPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(text)))
+	if parts == nil {
+		return 0, invalidCoordinate(value, ontology.NMEA_CoordinateFormat, axis, "expected [d]ddmm.mmmm")
+	}
+	var sign string
+	if strings.HasPrefix(parts[1], "-") || strings.HasPrefix(parts[1], "+") {
+		sign, parts[1] = parts[1][:1], parts[1][1:]
+	}
+	number, _ := strconv.ParseFloat(parts[1], 64)
+	var degrees = math.Floor(number / 100)
+	var minutes = number - degrees*100
+	if minutes >= 60 {
+		return 0, invalidCoordinate(value, ontology.NMEA_CoordinateFormat, axis, "minutes must be less than 60")
+	}
+	return applyHemisphere(value, degrees+minutes/60, sign, parts[2], ontology.NMEA_CoordinateFormat, axis)
+}
+
+// applyHemisphere gives degrees the sign of its hemisphere, which must belong to
+// axis and may not be combined with a sign.
+func applyHemisphere(value interface{}, degrees float64, sign string, hemisphere string, format ontology.CoordinateFormat, axis CoordinateAxis) (float64, error) {
+	switch {
+	case len(hemisphere) > 0 && len(sign) > 0:
+		return 0, invalidCoordinate(value, format, axis, "both a sign and a hemisphere")
+	case axis == LATITUDE_CoordinateAxis && (hemisphere == "E" || hemisphere == "W"),
+		axis == LONGITUDE_CoordinateAxis && (hemisphere == "N" || hemisphere == "S"):
+		return 0, invalidCoordinate(value, format, axis, fmt.Sprintf("hemisphere '%s' is not a %s hemisphere", hemisphere, axis))
+	case sign == "-" || hemisphere == "S" || hemisphere == "W":
+		return -degrees, nil
+	}
+	return degrees, nil
+}
@@ -0,0 +1,198 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"ontology-mapping-go-lib/models/ontology"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var gpsEpoch = time.Date(1980, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+// gpsLeapSeconds are the UTC times at which a leap second was inserted since the
+// GPS epoch, GPS time runs ahead of UTC by one more second after each of them.
+var gpsLeapSeconds = []time.Time{
+	time.Date(1981, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1982, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1983, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1985, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1988, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1991, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1992, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1993, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1994, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1996, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1997, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(1999, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2006, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2009, time.January, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2012, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2015, time.July, 1, 0, 0, 0, 0, time.UTC),
+	time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+}
+
+// minEventTimeUnix and maxEventTimeUnix bound the epoch event times, in Unix
+// seconds, to the years 1 to 9999.
+const (
+	minEventTimeUnix = -62135596800
+	maxEventTimeUnix = 253402300799
+)
+
+// locations caches the loaded timezones, loading one reads the zoneinfo database.
+var locations sync.Map
+
+// ParseEventTime reads an eventTime written in format, layouts without a zone
+// being read in timezone, UTC when empty. Values already holding a time.Time are
+// returned as they are.
+func ParseEventTime(value interface{}, format ontology.EventTimeFormat, timezone string) (time.Time, error) {
+	if eventTime, ok := value.(time.Time); ok {
+		return eventTime, nil
+	}
+	if value == nil {
+		return time.Time{}, NewMappingError(INVALID_EVENT_TIME_ErrorCode, "missing eventTime")
+	}
+	switch format {
+	case "", ontology.RFC3339_EventTimeFormat:
+		return toTime(value)
+	case ontology.EPOCH_S_EventTimeFormat, ontology.EPOCH_MS_EventTimeFormat, ontology.GPS_EventTimeFormat:
+		number, err := toDoubleValue(value)
+		if err != nil || math.IsNaN(number.(float64)) || math.IsInf(number.(float64), 0) {
+			return time.Time{}, invalidEventTime(value, format, "not a number")
+		}
+		var eventTime time.Time
+		var ok bool
+		switch format {
+		case ontology.EPOCH_S_EventTimeFormat:
+			eventTime, ok = epochTime(time.Unix(0, 0).UTC(), number.(float64), time.Second)
+		case ontology.EPOCH_MS_EventTimeFormat:
+			eventTime, ok = epochTime(time.Unix(0, 0).UTC(), number.(float64), time.Millisecond)
+		default:
+			eventTime, ok = gpsTime(number.(float64))
+		}
+		if !ok {
+			return time.Time{}, invalidEventTime(value, format, "out of range")
+		}
+		return eventTime, nil
+	default:
+		location, err := LoadTimezone(timezone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		var text string
+		switch typed := value.(type) {
+		case string:
+			text = typed
+		case float64:
+			text = strconv.FormatFloat(typed, 'f', -1, 64)
+		case json.Number:
+			text = typed.String()
+		default:
+			return time.Time{}, invalidEventTime(value, format, fmt.Sprintf("unsupported type %T", value))
+		}
+		eventTime, err := time.ParseInLocation(string(format), strings.TrimSpace(text), location)
+		if err != nil {
+			return time.Time{}, &MappingError{Code: INVALID_EVENT_TIME_ErrorCode, Message: err.Error(), Err: err}
+		}
+		return eventTime, nil
+	}
+}
+
+// ValidateEventTimeFormat checks that format is known or a Go time layout and
+// that timezone can be loaded.
+func ValidateEventTimeFormat(format ontology.EventTimeFormat, timezone string) error {
+	switch format {
+	case "", ontology.RFC3339_EventTimeFormat, ontology.EPOCH_S_EventTimeFormat, ontology.EPOCH_MS_EventTimeFormat, ontology.GPS_EventTimeFormat:
+	default:
+		// a layout is made of at least one element, which formatting replaces
+		if time.Unix(0, 0).UTC().Format(string(format)) == string(format) {
+			return NewMappingError(INVALID_EVENT_TIME_ErrorCode,
+				fmt.Sprintf("unknown 'eventTimeFormat' '%s', expected rfc3339, epoch_s, epoch_ms, gps or a Go time layout", format))
+		}
+	}
+	_, err := LoadTimezone(timezone)
+	return err
+}
+
+// LoadTimezone returns the location named timezone, UTC when empty.
+func LoadTimezone(timezone string) (*time.Location, error) {
+	if len(timezone) == 0 {
+		return time.UTC, nil
+	}
+	if location, ok := locations.Load(timezone); ok {
+		return location.(*time.Location), nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, &MappingError{Code: INVALID_EVENT_TIME_ErrorCode, Message: fmt.Sprintf("unknown timezone '%s'", timezone), Err: err}
+	}
+	locations.Store(timezone, location)
+	return location, nil
+}
+
+// ParseSamplePeriod reads a positive sample period given as a duration such as
+// "10m" or as a number of seconds.
+func ParseSamplePeriod(value interface{}) (time.Duration, error) {
+	var period time.Duration
+	switch typed := value.(type) {
+	case nil:
+		return 0, fmt.Errorf("missing sample period")
+	case bool:
+		return 0, fmt.Errorf("%v is neither a duration nor a number of seconds", value)
+	case string:
+		var text = strings.TrimSpace(typed)
+		if seconds, err := strconv.ParseFloat(text, 64); err == nil {
+			period = time.Duration(math.Round(seconds * float64(time.Second)))
+		} else if period, err = time.ParseDuration(text); err != nil {
+			return 0, fmt.Errorf("'%s' is neither a duration nor a number of seconds", typed)
+		}
+	default:
+		seconds, err := toDoubleValue(value)
+		if err != nil {
+			return 0, fmt.Errorf("%v is neither a duration nor a number of seconds", value)
+		}
+		period = time.Duration(math.Round(seconds.(float64) * float64(time.Second)))
+	}
+	if period <= 0 {
+		return 0, fmt.Errorf("%v is not positive", value)
+	}
+	return period, nil
+}
+
+func invalidEventTime(value interface{}, format ontology.EventTimeFormat, reason string) error {
+	return NewMappingError(INVALID_EVENT_TIME_ErrorCode, fmt.Sprintf("invalid %s eventTime %v: %s", format, value, reason))
+}
+
+// epochTime adds count units to epoch, keeping the fraction of a unit. It fails
+// on times outside the years 1 to 9999, which would overflow a time.Duration.
+func epochTime(epoch time.Time, count float64, unit time.Duration) (time.Time, bool) {
+	var unitsPerSecond = int64(time.Second / unit)
+	var whole = math.Floor(count)
+	var seconds = float64(epoch.Unix()) + whole/float64(unitsPerSecond)
+	if seconds < minEventTimeUnix || seconds > maxEventTimeUnix {
+		return time.Time{}, false
+	}
+	var units = int64(whole)
+	var nanoseconds = units%unitsPerSecond*int64(unit) + int64(math.Round((count-whole)*float64(unit)))
+	return time.Unix(epoch.Unix()+units/unitsPerSecond, nanoseconds).UTC(), true
+}
+
+// gpsTime converts seconds since the GPS epoch to UTC by removing the leap
+// seconds inserted before them.
+func gpsTime(seconds float64) (time.Time, bool) {
+	eventTime, ok := epochTime(gpsEpoch, seconds, time.Second)
+	if !ok {
+		return time.Time{}, false
+	}
+	var offset time.Duration
+	for _, leapSecond := range gpsLeapSeconds {
+		if !eventTime.Add(-offset - time.Second).Before(leapSecond) {
+			offset += time.Second
+		}
+	}
+	return eventTime.Add(-offset), true
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"context"
+	"sync"
+)
+
+// ExpressionEvaluation is one JMESPath template evaluated while applying an operation.
+type ExpressionEvaluation struct {
+	Expression string      `json:"expression"`
+	Result     interface{} `json:"result"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// ExpressionRecorder collects the evaluations made by RetrieveValuesContext when
+// it is attached to the context with WithExpressionRecorder.
+type ExpressionRecorder struct {
+	mutex       sync.Mutex
+	evaluations []ExpressionEvaluation
+}
+
+type expressionRecorderKey struct{}
+
+func WithExpressionRecorder(ctx context.Context, recorder *ExpressionRecorder) context.Context {
+	return context.WithValue(ctx, expressionRecorderKey{}, recorder)
+}
+
+func (recorder *ExpressionRecorder) Evaluations() []ExpressionEvaluation {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return append([]ExpressionEvaluation(nil), recorder.evaluations...)
+}
+
+func recordEvaluation(ctx context.Context, jmesExpression string, result interface{}, err error) {
+	recorder, ok := ctx.Value(expressionRecorderKey{}).(*ExpressionRecorder)
+	if !ok || !IsJmesExpression(jmesExpression) {
+		return
+	}
+	var evaluation = ExpressionEvaluation{Expression: jmesExpression, Result: result}
+	if err != nil {
+		evaluation.Error = err.Error()
+	}
+	recorder.mutex.Lock()
+	recorder.evaluations = append(recorder.evaluations, evaluation)
+	recorder.mutex.Unlock()
+}
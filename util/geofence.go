@@ -0,0 +1,198 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"ontology-mapping-go-lib/models/ontology"
+)
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6371008.8
+
+type geoJsonObject struct {
+	Type       string                 `json:"type"`
+	Id         interface{}            `json:"id"`
+	Features   []geoJsonObject        `json:"features"`
+	Geometry   *geoJsonObject         `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+	// Coordinates depend on the geometry type, they are decoded once it is known
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ParseGeoJsonZones reads the zones of a GeoJSON FeatureCollection or Feature.
+// Polygon and MultiPolygon features become polygon zones, Point features become
+// circle zones when their "radius" property gives a radius in meters. Zones are
+// named after the "name" property, then the feature id.
+func ParseGeoJsonZones(data []byte) ([]ontology.GeofenceZone, error) {
+	var object geoJsonObject
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, &MappingError{Code: INVALID_RULE_ErrorCode, Message: fmt.Sprintf("invalid GeoJSON: %v", err), Err: err}
+	}
+	var features []geoJsonObject
+	switch object.Type {
+	case "FeatureCollection":
+		features = object.Features
+	case "Feature":
+		features = []geoJsonObject{object}
+	default:
+		return nil, NewMappingError(INVALID_RULE_ErrorCode, fmt.Sprintf("unsupported GeoJSON type '%s', expected 'FeatureCollection' or 'Feature'", object.Type))
+	}
+	var zones []ontology.GeofenceZone
+	for i, feature := range features {
+		featureZones, err := featureZones(feature)
+		if err != nil {
+			return nil, NewMappingError(INVALID_RULE_ErrorCode, fmt.Sprintf("GeoJSON feature %d: %v", i, err))
+		}
+		zones = append(zones, featureZones...)
+	}
+	return zones, nil
+}
+
+func featureZones(feature geoJsonObject) ([]ontology.GeofenceZone, error) {
+	if feature.Geometry == nil {
+		return nil, fmt.Errorf("no geometry")
+	}
+	var name string
+	if value, ok := feature.Properties["name"]; ok && value != nil {
+		name = fmt.Sprint(value)
+	} else if feature.Id != nil {
+		name = fmt.Sprint(feature.Id)
+	}
+	switch feature.Geometry.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &polygon); err != nil {
+			return nil, err
+		}
+		return []ontology.GeofenceZone{{Name: name, Polygon: polygon}}, nil
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &polygons); err != nil {
+			return nil, err
+		}
+		var zones []ontology.GeofenceZone
+		for _, polygon := range polygons {
+			zones = append(zones, ontology.GeofenceZone{Name: name, Polygon: polygon})
+		}
+		return zones, nil
+	case "Point":
+		var center []float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &center); err != nil {
+			return nil, err
+		}
+		radius, ok := feature.Properties["radius"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("a Point needs a numeric 'radius' property")
+		}
+		return []ontology.GeofenceZone{{Name: name, Center: center, Radius: radius}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type '%s'", feature.Geometry.Type)
+	}
+}
+
+// ValidateZone checks that zone is named and is either a polygon whose rings hold
+// at least 3 distinct positions or a circle with a positive radius.
+func ValidateZone(zone ontology.GeofenceZone) error {
+	if len(zone.Name) == 0 {
+		return fmt.Errorf("zone without name")
+	}
+	switch {
+	case len(zone.Polygon) > 0 && len(zone.Center) > 0:
+		return fmt.Errorf("zone '%s' is both a polygon and a circle", zone.Name)
+	case len(zone.Polygon) > 0:
+		for _, ring := range zone.Polygon {
+			for _, position := range ring {
+				if err := validatePosition(position); err != nil {
+					return fmt.Errorf("zone '%s': %v", zone.Name, err)
+				}
+			}
+			if distinctPositions(openRing(ring)) < 3 {
+				return fmt.Errorf("zone '%s' has a ring of less than 3 distinct positions", zone.Name)
+			}
+		}
+		return nil
+	case len(zone.Center) > 0:
+		if err := validatePosition(zone.Center); err != nil {
+			return fmt.Errorf("zone '%s': %v", zone.Name, err)
+		}
+		if zone.Radius <= 0 {
+			return fmt.Errorf("zone '%s' needs a positive 'radius'", zone.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("zone '%s' needs a 'polygon' or a 'center'", zone.Name)
+	}
+}
+
+func validatePosition(position []float64) error {
+	if len(position) != 2 && len(position) != 3 {
+		return fmt.Errorf("invalid position %v, expected [longitude, latitude]", position)
+	}
+	if position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+		return fmt.Errorf("position %v is out of range", position)
+	}
+	return nil
+}
+
+// ZoneContains tells whether the position longitude, latitude lies in zone, on
+// a plane for polygons, which suits zones of a few kilometers, and on a sphere
+// for circles.
+func ZoneContains(zone ontology.GeofenceZone, longitude float64, latitude float64) bool {
+	if len(zone.Polygon) == 0 {
+		return len(zone.Center) >= 2 && distance(zone.Center[0], zone.Center[1], longitude, latitude) <= zone.Radius
+	}
+	if !ringContains(zone.Polygon[0], longitude, latitude) {
+		return false
+	}
+	for _, hole := range zone.Polygon[1:] {
+		if ringContains(hole, longitude, latitude) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains casts a ray from the position and counts the edges it crosses.
+func ringContains(ring [][]float64, x float64, y float64) bool {
+	ring = openRing(ring)
+	var inside bool
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		if len(ring[i]) < 2 || len(ring[j]) < 2 {
+			continue
+		}
+		var xi, yi, xj, yj = ring[i][0], ring[i][1], ring[j][0], ring[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// distinctPositions counts the distinct [longitude, latitude] pairs of ring.
+func distinctPositions(ring [][]float64) int {
+	var positions = make(map[[2]float64]bool)
+	for _, position := range ring {
+		positions[[2]float64{position[0], position[1]}] = true
+	}
+	return len(positions)
+}
+
+// openRing drops the closing position of a GeoJSON ring.
+func openRing(ring [][]float64) [][]float64 {
+	var last = len(ring) - 1
+	if last > 0 && len(ring[0]) >= 2 && len(ring[last]) >= 2 && ring[0][0] == ring[last][0] && ring[0][1] == ring[last][1] {
+		return ring[:last]
+	}
+	return ring
+}
+
+// distance is the haversine distance in meters between two positions.
+func distance(longitude1 float64, latitude1 float64, longitude2 float64, latitude2 float64) float64 {
+	var toRadians = math.Pi / 180
+	var deltaLatitude = (latitude2 - latitude1) * toRadians
+	var deltaLongitude = (longitude2 - longitude1) * toRadians
+	var a = math.Pow(math.Sin(deltaLatitude/2), 2) +
+		math.Cos(latitude1*toRadians)*math.Cos(latitude2*toRadians)*math.Pow(math.Sin(deltaLongitude/2), 2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
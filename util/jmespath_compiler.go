@@ -0,0 +1,66 @@
+package util
+
+import (
+	"context"
+	"strings"
+)
+import "git.int.actility.com/Thingpark-X/go-jmespath"
+
+// CompiledExpressions maps "{{ }}" templates to their parsed expression, so that
+// RetrieveValuesContext does not lex and parse them per message. It is filled
+// before being shared and only read afterwards.
+type CompiledExpressions map[string]*jmespath.JMESPath
+
+type compiledExpressionsKey struct{}
+
+// WithCompiledExpressions makes RetrieveValuesContext evaluate the templates of
+// expressions with their parsed form.
+func WithCompiledExpressions(ctx context.Context, expressions CompiledExpressions) context.Context {
+	return context.WithValue(ctx, compiledExpressionsKey{}, expressions)
+}
+
+func compiledExpressionsFrom(ctx context.Context) CompiledExpressions {
+	expressions, _ := ctx.Value(compiledExpressionsKey{}).(CompiledExpressions)
+	return expressions
+}
+
+// Compile parses jmesExpression into expressions, strings that are not a "{{ }}"
+// template are ignored.
+func (expressions CompiledExpressions) Compile(jmesExpression string) error {
+	if !IsJmesExpression(jmesExpression) {
+		return nil
+	}
+	if _, ok := expressions[jmesExpression]; ok {
+		return nil
+	}
+	compiled, err := jmespath.Compile(stripTemplate(jmesExpression))
+	if err != nil {
+		return err
+	}
+	expressions[jmesExpression] = compiled
+	return nil
+}
+
+// ValidateExpression reports whether a "{{ }}" template compiles, without keeping it.
+func ValidateExpression(jmesExpression string) error {
+	if !IsJmesExpression(jmesExpression) {
+		return nil
+	}
+	_, err := jmespath.Compile(stripTemplate(jmesExpression))
+	return err
+}
+
+func IsJmesExpression(jmesExpression string) bool {
+	return strings.Contains(jmesExpression, "{{") && strings.Contains(jmesExpression, "}}")
+}
+
+func stripTemplate(jmesExpression string) string {
+	return strings.Replace(strings.Replace(jmesExpression, "{{", "", -1), "}}", "", -1)
+}
+
+func search(expressions CompiledExpressions, jmesExpression string, data interface{}) (interface{}, error) {
+	if compiled, ok := expressions[jmesExpression]; ok {
+		return compiled.Search(data)
+	}
+	return jmespath.Search(stripTemplate(jmesExpression), data)
+}
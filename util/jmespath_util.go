@@ -1,25 +1,29 @@
 package util
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
 	"time"
 )
 import "ontology-mapping-go-lib/models/flow"
-import "git.int.actility.com/Thingpark-X/go-jmespath"
+import "ontology-mapping-go-lib/models/ontology"
 import "strings"
 
 func RetrieveValues(jmesExpression string, message *interface{}) (interface{}, error) {
+	return retrieveValues(nil, jmesExpression, message)
+}
+
+func retrieveValues(expressions CompiledExpressions, jmesExpression string, message *interface{}) (interface{}, error) {
 	if jmesExpression == "" {
 		return nil, nil
 	}
-	if strings.Contains(jmesExpression, "{{") && strings.Contains(jmesExpression, "}}") {
-		var str = strings.Replace(strings.Replace(jmesExpression, "{{", "", -1), "}}", "", -1)
-		var searchResult, err = jmespath.Search(str, *message)
+	if IsJmesExpression(jmesExpression) {
+		var searchResult, err = search(expressions, jmesExpression, *message)
 		if err != nil {
-			return nil, err
+			return nil, &MappingError{Code: INVALID_EXPRESSION_ErrorCode, Expression: jmesExpression, Message: err.Error(), Err: err}
 		}
 		if searchResult != nil {
 			return searchResult, nil
@@ -28,7 +32,31 @@ func RetrieveValues(jmesExpression string, message *interface{}) (interface{}, e
 	return nil, nil
 }
 
+// RetrieveValuesContext is RetrieveValues honouring ctx, using the expressions
+// compiled by WithCompiledExpressions. ctx is checked before the search, so an
+// operation stops at its next evaluation once its deadline has passed.
+func RetrieveValuesContext(ctx context.Context, jmesExpression string, message *interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	value, err := retrieveValues(compiledExpressionsFrom(ctx), jmesExpression, message)
+	recordEvaluation(ctx, jmesExpression, value, err)
+	return value, err
+}
+
 func ExtractRecords(pointParams PointParams, point string) ([]flow.Record, error) {
+	return ExtractRecordsContext(context.Background(), pointParams, point)
+}
+
+func ExtractRecordsContext(ctx context.Context, pointParams PointParams, point string) ([]flow.Record, error) {
+	records, err := extractRecords(ctx, pointParams, point)
+	if err != nil {
+		return nil, WithKey(err, point)
+	}
+	return records, nil
+}
+
+func extractRecords(ctx context.Context, pointParams PointParams, point string) ([]flow.Record, error) {
 	var recordList []flow.Record
 	var value = toArray(pointParams.Values)
 	var lng = toArray(pointParams.Longitude)
@@ -36,16 +64,31 @@ func ExtractRecords(pointParams PointParams, point string) ([]flow.Record, error
 	var alt = toArray(pointParams.Altitude)
 	var eventTime = toArray(pointParams.EventTime)
 	var err error = nil
+	if pointParams.SamplePeriod > 0 {
+		var count = len(lng)
+		if pointParams.IsValue {
+			count = len(value)
+		}
+		if eventTime, err = sampleEventTimes(pointParams, eventTime, count); err != nil {
+			return nil, err
+		}
+	}
 	_, err = checkCardinality(pointParams, value, lng, lat, alt, eventTime, point)
 	if err == nil {
 		var validTime time.Time
 		var la, lg, al float64
 		if len(value) > 0 && len(lng) > 0 && len(alt) > 0 {
 			for i := 0; i < len(eventTime); i++ {
-				validTime, err = toTime(eventTime[i])
-				la, err = toDouble(lat[i])
-				lg, err = toDouble(lng[i])
-				al, err = toDouble(alt[i])
+				if err = ctx.Err(); err != nil {
+					return nil, err
+				}
+				if validTime, err = ParseEventTime(eventTime[i], pointParams.EventTimeFormat, pointParams.Timezone); err != nil {
+					return nil, err
+				}
+				lg, la, err = toLngLat(pointParams, lng[i], lat[i])
+				if err == nil {
+					al, err = toDouble(alt[i])
+				}
 				if err != nil {
 					return nil, err
 				}
@@ -54,9 +97,13 @@ func ExtractRecords(pointParams PointParams, point string) ([]flow.Record, error
 			}
 		} else if len(value) > 0 && len(lng) > 0 {
 			for i := 0; i < len(eventTime); i++ {
-				validTime, err = toTime(eventTime[i])
-				la, err = toDouble(lat[i])
-				lg, err = toDouble(lng[i])
+				if err = ctx.Err(); err != nil {
+					return nil, err
+				}
+				if validTime, err = ParseEventTime(eventTime[i], pointParams.EventTimeFormat, pointParams.Timezone); err != nil {
+					return nil, err
+				}
+				lg, la, err = toLngLat(pointParams, lng[i], lat[i])
 				if err != nil {
 					return nil, err
 				}
@@ -65,10 +112,16 @@ func ExtractRecords(pointParams PointParams, point string) ([]flow.Record, error
 			}
 		} else if len(lng) > 0 && len(alt) > 0 {
 			for i := 0; i < len(eventTime); i++ {
-				validTime, err = toTime(eventTime[i])
-				la, err = toDouble(lat[i])
-				lg, err = toDouble(lng[i])
-				al, err = toDouble(alt[i])
+				if err = ctx.Err(); err != nil {
+					return nil, err
+				}
+				if validTime, err = ParseEventTime(eventTime[i], pointParams.EventTimeFormat, pointParams.Timezone); err != nil {
+					return nil, err
+				}
+				lg, la, err = toLngLat(pointParams, lng[i], lat[i])
+				if err == nil {
+					al, err = toDouble(alt[i])
+				}
 				if err != nil {
 					return nil, err
 				}
@@ -77,9 +130,13 @@ func ExtractRecords(pointParams PointParams, point string) ([]flow.Record, error
 			}
 		} else if len(lng) > 0 {
 			for i := 0; i < len(eventTime); i++ {
-				validTime, err = toTime(eventTime[i])
-				la, err = toDouble(lat[i])
-				lg, err = toDouble(lng[i])
+				if err = ctx.Err(); err != nil {
+					return nil, err
+				}
+				if validTime, err = ParseEventTime(eventTime[i], pointParams.EventTimeFormat, pointParams.Timezone); err != nil {
+					return nil, err
+				}
+				lg, la, err = toLngLat(pointParams, lng[i], lat[i])
 				if err != nil {
 					return nil, err
 				}
@@ -88,7 +145,10 @@ func ExtractRecords(pointParams PointParams, point string) ([]flow.Record, error
 			}
 		} else if len(value) > 0 {
 			for i := 0; i < len(eventTime); i++ {
-				validTime, err = toTime(eventTime[i])
+				if err = ctx.Err(); err != nil {
+					return nil, err
+				}
+				validTime, err = ParseEventTime(eventTime[i], pointParams.EventTimeFormat, pointParams.Timezone)
 				if err != nil {
 					return nil, err
 				}
@@ -104,69 +164,83 @@ func ExtractRecords(pointParams PointParams, point string) ([]flow.Record, error
 }
 
 func ExtractMessage(message interface{}, operation interface{}) (interface{}, error) {
+	return ExtractMessageContext(context.Background(), message, operation)
+}
+
+func ExtractMessageContext(ctx context.Context, message interface{}, operation interface{}) (interface{}, error) {
 	var err error
 	if reflect.TypeOf(operation).Kind() == reflect.String &&
 		strings.Contains(operation.(string), "{{") && strings.Contains(operation.(string), "}}") {
 		var retrievedJmesValue interface{}
-		retrievedJmesValue, err = RetrieveValues(operation.(string), &message)
+		retrievedJmesValue, err = RetrieveValuesContext(ctx, operation.(string), &message)
 		if err != nil {
 			return nil, err
 		}
 		if retrievedJmesValue != nil && reflect.TypeOf(retrievedJmesValue).Kind() == reflect.Map {
 			return retrievedJmesValue, nil
 		} else {
-			return nil, errors.New("expected object for 'message' but returned value node or null")
+			return nil, &MappingError{Code: UNEXPECTED_RESULT_ErrorCode, Expression: operation.(string), Message: "expected object for 'message' but returned value node or null"}
 		}
 	}
 	if reflect.TypeOf(operation).Kind() != reflect.Map {
-		return nil, errors.New("expected object but is a value node")
+		return nil, NewMappingError(UNEXPECTED_RESULT_ErrorCode, "expected object but is a value node")
 	}
-	return extractMessageRecursion(message, operation)
+	return extractMessageRecursion(ctx, message, operation)
 }
 
 func ExtractCommands(message interface{}, operation interface{}) (interface{}, error) {
+	return ExtractCommandsContext(context.Background(), message, operation)
+}
+
+func ExtractCommandsContext(ctx context.Context, message interface{}, operation interface{}) (interface{}, error) {
 	var err error
 	if reflect.TypeOf(operation).Kind() == reflect.String &&
 		strings.Contains(operation.(string), "{{") && strings.Contains(operation.(string), "}}") {
 		var retrievedJmesValue interface{}
-		retrievedJmesValue, err = RetrieveValues(operation.(string), &message)
+		retrievedJmesValue, err = RetrieveValuesContext(ctx, operation.(string), &message)
 		if err != nil {
 			return nil, err
 		}
 		if retrievedJmesValue != nil {
 			return retrievedJmesValue, nil
 		} else {
-			return nil, errors.New("retrieved value is null or not a map")
+			return nil, &MappingError{Code: UNEXPECTED_RESULT_ErrorCode, Expression: operation.(string), Message: "retrieved value is null or not a map"}
 		}
 	}
 	if reflect.TypeOf(operation).Kind() != reflect.Map {
 		return operation, nil
 	}
-	return extractMessageRecursion(message, operation)
+	return extractMessageRecursion(ctx, message, operation)
 }
 
-func extractMessageRecursion(message interface{}, operation interface{}) (interface{}, error) {
+func extractMessageRecursion(ctx context.Context, message interface{}, operation interface{}) (interface{}, error) {
 	var returnJson = make(map[string]interface{})
 	fields := operation.(map[string]interface{})
 	var err error
 	for key, element := range fields {
-		if reflect.TypeOf(element).Kind() == reflect.Map {
-			returnJson[key], err = extractMessageRecursion(message, element)
+		switch typed := element.(type) {
+		case map[string]interface{}:
+			returnJson[key], err = extractMessageRecursion(ctx, message, typed)
 			if err != nil {
 				return nil, err
 			}
-		} else if strings.Contains(element.(string), "{{") && strings.Contains(element.(string), "}}") {
+		case string:
+			if !strings.Contains(typed, "{{") || !strings.Contains(typed, "}}") {
+				returnJson[key] = typed
+				continue
+			}
 			var value interface{}
-			value, err = RetrieveValues(element.(string), &message)
+			value, err = RetrieveValuesContext(ctx, typed, &message)
 			if err != nil {
 				return nil, err
 			}
 			if value != nil {
 				returnJson[key] = value
 			} else {
-				return nil, errors.New("nothing could be extracted from the jmes expression" + key)
+				return nil, &MappingError{Code: UNEXPECTED_RESULT_ErrorCode, Key: key, Expression: typed, Message: "nothing could be extracted from the jmes expression" + key}
 			}
-		} else {
+		default:
+			// numbers, booleans, arrays and nulls are constants
 			returnJson[key] = element
 		}
 	}
@@ -176,25 +250,50 @@ func extractMessageRecursion(message interface{}, operation interface{}) (interf
 func checkCardinality(pointParams PointParams, value []interface{}, lng []interface{}, lat []interface{}, alt []interface{}, eventTime []interface{}, point string) (int, error) {
 	if pointParams.IsValue {
 		if len(value) > 0 && len(value) != len(eventTime) {
-			return 0, errors.New("there is a mismatch in cardinality for 'value' and 'eventTime' " + point)
+			return 0, &MappingError{Code: CARDINALITY_MISMATCH_ErrorCode, Key: point, Message: "there is a mismatch in cardinality for 'value' and 'eventTime' " + point}
 		}
 	}
 	if pointParams.IsCoordinate {
 		if len(lng) > 0 && len(lng) != len(eventTime) {
-			return 0, errors.New("there is a mismatch in cardinality between 'coordinates' and 'eventTime' fields " + point)
+			return 0, &MappingError{Code: CARDINALITY_MISMATCH_ErrorCode, Key: point, Message: "there is a mismatch in cardinality between 'coordinates' and 'eventTime' fields " + point}
 
 		} else if len(lng) != len(lat) || (pointParams.IsAltitude && len(lng) != len(alt)) {
-			return 0, errors.New("there is a mismatch in cardinality between 'latitude' and 'longitude' and 'altitude' fields " + point)
+			return 0, &MappingError{Code: CARDINALITY_MISMATCH_ErrorCode, Key: point, Message: "there is a mismatch in cardinality between 'latitude' and 'longitude' and 'altitude' fields " + point}
 		}
 	}
 	if pointParams.IsValue && pointParams.IsCoordinate {
 		if len(value) != len(lng) {
-			return 0, errors.New("there is a mismatch in cardinality for 'value' and 'coordinates' " + point)
+			return 0, &MappingError{Code: CARDINALITY_MISMATCH_ErrorCode, Key: point, Message: "there is a mismatch in cardinality for 'value' and 'coordinates' " + point}
 		}
 	}
 	return 0, nil
 }
 
+// sampleEventTimes gives count records the single base time held by eventTime
+// shifted by one sample period more for each record.
+func sampleEventTimes(pointParams PointParams, eventTime []interface{}, count int) ([]interface{}, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	if len(eventTime) != 1 {
+		return nil, NewMappingError(CARDINALITY_MISMATCH_ErrorCode,
+			fmt.Sprintf("'eventTime' must give a single base time when 'samplePeriod' is set, got %d", len(eventTime)))
+	}
+	base, err := ParseEventTime(eventTime[0], pointParams.EventTimeFormat, pointParams.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	var period = pointParams.SamplePeriod
+	if pointParams.SampleDirection == ontology.BACKWARD_SampleDirection {
+		period = -period
+	}
+	var eventTimes = make([]interface{}, count)
+	for i := range eventTimes {
+		eventTimes[i] = base.Add(time.Duration(i) * period)
+	}
+	return eventTimes, nil
+}
+
 func toArray(point interface{}) []interface{} {
 	var result []interface{}
 	if point == nil {
@@ -220,26 +319,35 @@ func toTime(param interface{}) (time.Time, error) {
 	if reflect.TypeOf(param).Kind() == reflect.String {
 		resTime, err := time.Parse(time.RFC3339, param.(string))
 		if err != nil {
-			return time.Time{}, err
+			return time.Time{}, &MappingError{Code: INVALID_EVENT_TIME_ErrorCode, Message: err.Error(), Err: err}
 		}
 		return resTime, nil
 	} else if reflect.TypeOf(param).Kind() == reflect.TypeOf(time.Time{}).Kind() {
 		return param.(time.Time), nil
 	} else {
-		return time.Time{}, errors.New("error while converting interface to time.Time")
+		return time.Time{}, NewMappingError(INVALID_EVENT_TIME_ErrorCode, "error while converting interface to time.Time")
+	}
+}
+func toLngLat(pointParams PointParams, lng interface{}, lat interface{}) (float64, float64, error) {
+	longitude, err := ParseCoordinate(lng, pointParams.CoordinateFormat, pointParams.CoordinateScale, LONGITUDE_CoordinateAxis)
+	if err != nil {
+		return 0, 0, err
 	}
+	latitude, err := ParseCoordinate(lat, pointParams.CoordinateFormat, pointParams.CoordinateScale, LATITUDE_CoordinateAxis)
+	return longitude, latitude, err
 }
+
 func toDouble(param interface{}) (float64, error) {
 	if reflect.TypeOf(param).Kind() == reflect.Float64 {
 		return param.(float64), nil
 	} else if reflect.TypeOf(param).Kind() == reflect.String {
 		val, err := strconv.ParseFloat(param.(string), 64)
 		if err != nil {
-			return 0, err
+			return 0, &MappingError{Code: INVALID_COORDINATES_ErrorCode, Message: err.Error(), Err: err}
 		}
 		return val, nil
 	} else {
-		return 0, errors.New("error while converting interface to double")
+		return 0, NewMappingError(INVALID_COORDINATES_ErrorCode, "error while converting interface to double")
 	}
 }
 
@@ -303,6 +411,12 @@ func cloneSubAccount(subAccount *flow.Account) *flow.Account {
 		return nil
 	}
 }
+// CloneValue deep copies a JSON like value, so that writing it into a message
+// does not share it with the mapping it comes from.
+func CloneValue(value interface{}) interface{} {
+	return cloneInterface(value)
+}
+
 func cloneInterface(inter interface{}) interface{} {
 	if inter!=nil{
 		data, _ := json.Marshal(inter)
@@ -338,7 +452,7 @@ func cloneRecord(records []flow.Record) []flow.Record {
 		record.EventTime = j.EventTime
 		if len(j.Coordinates) == 3 {
 			record.Coordinates = []float64{j.Coordinates[0], j.Coordinates[1], j.Coordinates[2]}
-		} else if len(j.Coordinates) == 3 {
+		} else if len(j.Coordinates) == 2 {
 			record.Coordinates = []float64{j.Coordinates[0], j.Coordinates[1]}
 		} else {
 			record.Coordinates = nil
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"fmt"
+	"ontology-mapping-go-lib/models/flow"
+	"ontology-mapping-go-lib/models/ontology"
+)
+
+type ErrorCode string
+
+const (
+	UNKNOWN_OPERATION_ErrorCode    ErrorCode = "unknownOperation"
+	INVALID_EXPRESSION_ErrorCode   ErrorCode = "invalidExpression"
+	CARDINALITY_MISMATCH_ErrorCode ErrorCode = "cardinalityMismatch"
+	INVALID_COORDINATES_ErrorCode  ErrorCode = "invalidCoordinates"
+	INVALID_EVENT_TIME_ErrorCode   ErrorCode = "invalidEventTime"
+	UNEXPECTED_RESULT_ErrorCode    ErrorCode = "unexpectedResult"
+	OPERATION_FAILED_ErrorCode     ErrorCode = "operationFailed"
+	INVALID_POINT_TYPE_ErrorCode   ErrorCode = "invalidPointType"
+	EMPTY_POINTS_ErrorCode         ErrorCode = "emptyPoints"
+	INVALID_COMMAND_ErrorCode      ErrorCode = "invalidCommand"
+	CONVERSION_FAILED_ErrorCode    ErrorCode = "conversionFailed"
+	UNKNOWN_UNIT_ErrorCode         ErrorCode = "unknownUnit"
+	INCOMPATIBLE_UNITS_ErrorCode   ErrorCode = "incompatibleUnits"
+	POINT_COLLISION_ErrorCode      ErrorCode = "pointCollision"
+	INVALID_RULE_ErrorCode         ErrorCode = "invalidRule"
+	DUPLICATE_RECORD_ErrorCode     ErrorCode = "duplicateRecord"
+	INVALID_FIELD_ErrorCode        ErrorCode = "invalidField"
+	COMMAND_REJECTED_ErrorCode     ErrorCode = "commandRejected"
+)
+
+// Sentinels to match a MappingError by code with errors.Is.
+var (
+	ErrUnknownOperation    = &MappingError{Code: UNKNOWN_OPERATION_ErrorCode}
+	ErrInvalidExpression   = &MappingError{Code: INVALID_EXPRESSION_ErrorCode}
+	ErrCardinalityMismatch = &MappingError{Code: CARDINALITY_MISMATCH_ErrorCode}
+	ErrInvalidCoordinates  = &MappingError{Code: INVALID_COORDINATES_ErrorCode}
+	ErrInvalidEventTime    = &MappingError{Code: INVALID_EVENT_TIME_ErrorCode}
+	ErrUnexpectedResult    = &MappingError{Code: UNEXPECTED_RESULT_ErrorCode}
+	ErrOperationFailed     = &MappingError{Code: OPERATION_FAILED_ErrorCode}
+	ErrInvalidPointType    = &MappingError{Code: INVALID_POINT_TYPE_ErrorCode}
+	ErrEmptyPoints         = &MappingError{Code: EMPTY_POINTS_ErrorCode}
+	ErrInvalidCommand      = &MappingError{Code: INVALID_COMMAND_ErrorCode}
+	ErrConversionFailed    = &MappingError{Code: CONVERSION_FAILED_ErrorCode}
+	ErrUnknownUnit         = &MappingError{Code: UNKNOWN_UNIT_ErrorCode}
+	ErrIncompatibleUnits   = &MappingError{Code: INCOMPATIBLE_UNITS_ErrorCode}
+	ErrPointCollision      = &MappingError{Code: POINT_COLLISION_ErrorCode}
+	ErrInvalidRule         = &MappingError{Code: INVALID_RULE_ErrorCode}
+	ErrDuplicateRecord     = &MappingError{Code: DUPLICATE_RECORD_ErrorCode}
+	ErrInvalidField        = &MappingError{Code: INVALID_FIELD_ErrorCode}
+	ErrCommandRejected     = &MappingError{Code: COMMAND_REJECTED_ErrorCode}
+)
+
+// MappingError describes why a mapping failed on a message. Index and Op are only
+// meaningful once the error went through OperationService, Key is the point or
+// command the failure relates to and Expression the JMESPath template involved.
+type MappingError struct {
+	Code       ErrorCode
+	Index      int
+	Op         string
+	Key        string
+	Expression string
+	Message    string
+	Err        error
+}
+
+func (mappingError *MappingError) Error() string {
+	if len(mappingError.Op) == 0 {
+		return mappingError.Message
+	}
+	var message = fmt.Sprintf("operation %d '%s'", mappingError.Index, mappingError.Op)
+	if len(mappingError.Key) > 0 {
+		message += fmt.Sprintf(", key '%s'", mappingError.Key)
+	}
+	if len(mappingError.Expression) > 0 {
+		message += fmt.Sprintf(", expression '%s'", mappingError.Expression)
+	}
+	return message + ": " + mappingError.Message
+}
+
+func (mappingError *MappingError) Unwrap() error {
+	return mappingError.Err
+}
+
+func (mappingError *MappingError) Is(target error) bool {
+	targetError, ok := target.(*MappingError)
+	return ok && len(targetError.Code) > 0 && targetError.Code == mappingError.Code
+}
+
+func (mappingError *MappingError) FlowErrorInfo() flow.ErrorInfo {
+	return flow.ErrorInfo{Code: string(mappingError.Code), Message: mappingError.Error()}
+}
+
+func (mappingError *MappingError) OntologyErrorInfo() ontology.ErrorInfo {
+	return ontology.ErrorInfo{Code: string(mappingError.Code), Message: mappingError.Error()}
+}
+
+func NewMappingError(code ErrorCode, message string) *MappingError {
+	return &MappingError{Code: code, Message: message}
+}
+
+// WithKey records the point or command key on err when it is a MappingError
+// that does not carry one yet.
+func WithKey(err error, key string) error {
+	if mappingError, ok := err.(*MappingError); ok && len(mappingError.Key) == 0 {
+		var annotated = *mappingError
+		annotated.Key = key
+		return &annotated
+	}
+	return err
+}
+
+// WithOperation records the failing operation on err, errors that are not a
+// MappingError are wrapped with OPERATION_FAILED_ErrorCode.
+func WithOperation(err error, index int, op string) error {
+	if mappingError, ok := err.(*MappingError); ok {
+		var annotated = *mappingError
+		annotated.Index = index
+		annotated.Op = op
+		return &annotated
+	}
+	return &MappingError{Code: OPERATION_FAILED_ErrorCode, Index: index, Op: op, Message: err.Error(), Err: err}
+}
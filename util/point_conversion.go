@@ -0,0 +1,165 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"ontology-mapping-go-lib/models/flow"
+	"strconv"
+	"strings"
+)
+
+// ConvertPoint coerces the value of every record of point to the declared
+// point.Type_. Points without a type, or typed obix, xml or object, are returned
+// unchanged as well as records without a value.
+func ConvertPoint(key string, point flow.Point) (flow.Point, error) {
+	switch point.Type_ {
+	case flow.STRING__Type, flow.INT64__Type, flow.DOUBLE_Type, flow.BOOLEAN_Type:
+	default:
+		return point, nil
+	}
+	var records = make([]flow.Record, len(point.Records))
+	for i, record := range point.Records {
+		records[i] = record
+		if record.Value == nil {
+			continue
+		}
+		value, err := ConvertValue(record.Value, point.Type_)
+		if err != nil {
+			return point, &MappingError{Code: CONVERSION_FAILED_ErrorCode, Key: key,
+				Message: fmt.Sprintf("cannot convert value %v of record %d to %s: %v", record.Value, i, point.Type_, err), Err: err}
+		}
+		records[i].Value = value
+	}
+	point.Records = records
+	return point, nil
+}
+
+// ConvertValue coerces value to pointType: strings are parsed, numbers become
+// booleans when they are 1 or 0 and doubles are truncated to int64 when they fit.
+func ConvertValue(value interface{}, pointType flow.PointType) (interface{}, error) {
+	switch pointType {
+	case flow.STRING__Type:
+		return toStringValue(value)
+	case flow.INT64__Type:
+		return toInt64Value(value)
+	case flow.DOUBLE_Type:
+		return toDoubleValue(value)
+	case flow.BOOLEAN_Type:
+		return toBooleanValue(value)
+	default:
+		return value, nil
+	}
+}
+
+func toStringValue(value interface{}) (interface{}, error) {
+	switch typed := value.(type) {
+	case string:
+		return typed, nil
+	case bool:
+		return strconv.FormatBool(typed), nil
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(typed, 10), nil
+	case int:
+		return strconv.Itoa(typed), nil
+	case json.Number:
+		return typed.String(), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func toInt64Value(value interface{}) (interface{}, error) {
+	switch typed := value.(type) {
+	case int64:
+		return typed, nil
+	case int:
+		return int64(typed), nil
+	case bool:
+		if typed {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		if integer, err := strconv.ParseInt(strings.TrimSpace(typed), 10, 64); err == nil {
+			return integer, nil
+		}
+		double, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a number", typed)
+		}
+		return truncateInt64(double)
+	case json.Number:
+		return toInt64Value(typed.String())
+	default:
+		double, err := toDoubleValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return truncateInt64(double.(float64))
+	}
+}
+
+// truncateInt64 drops the fractional part of double, which must lie in the int64 range.
+func truncateInt64(double float64) (interface{}, error) {
+	if math.IsNaN(double) || double < math.MinInt64 || double >= math.MaxInt64 {
+		return nil, fmt.Errorf("%v is out of the int64 range", double)
+	}
+	return int64(double), nil
+}
+
+func toDoubleValue(value interface{}) (interface{}, error) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, nil
+	case float32:
+		return float64(typed), nil
+	case int64:
+		return float64(typed), nil
+	case int:
+		return float64(typed), nil
+	case bool:
+		if typed {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case string:
+		double, err := strconv.ParseFloat(strings.TrimSpace(typed), 64)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a number", typed)
+		}
+		return double, nil
+	case json.Number:
+		return toDoubleValue(typed.String())
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func toBooleanValue(value interface{}) (interface{}, error) {
+	switch typed := value.(type) {
+	case bool:
+		return typed, nil
+	case string:
+		boolean, err := strconv.ParseBool(strings.TrimSpace(typed))
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a boolean", typed)
+		}
+		return boolean, nil
+	default:
+		double, err := toDoubleValue(value)
+		if err != nil {
+			return nil, err
+		}
+		switch double.(float64) {
+		case 1:
+			return true, nil
+		case 0:
+			return false, nil
+		default:
+			return nil, fmt.Errorf("%v is neither 1 nor 0", double)
+		}
+	}
+}
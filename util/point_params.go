@@ -1,5 +1,10 @@
 package util
 
+import (
+	"ontology-mapping-go-lib/models/ontology"
+	"time"
+)
+
 type PointParams struct {
 	Values       interface{}
 	EventTime    interface{}
@@ -9,4 +14,14 @@ type PointParams struct {
 	IsAltitude   bool
 	IsValue      bool
 	IsCoordinate bool
+	// CoordinateFormat and CoordinateScale tell how Longitude and Latitude are read
+	CoordinateFormat ontology.CoordinateFormat
+	CoordinateScale  float64
+	// EventTimeFormat and Timezone tell how EventTime is read
+	EventTimeFormat ontology.EventTimeFormat
+	Timezone        string
+	// SamplePeriod, when positive, spreads the records one period apart from the
+	// single base time given by EventTime, in SampleDirection
+	SamplePeriod    time.Duration
+	SampleDirection ontology.SampleDirection
 }
@@ -0,0 +1,124 @@
+package util
+
+import (
+	"fmt"
+	"sync"
+)
+
+// unit converts a value to the base unit of its dimension with (value + offset) * factor.
+type unit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+var (
+	unitsMutex sync.RWMutex
+	units      = map[string]unit{
+		// temperature, base Cel
+		"Cel":  {dimension: "temperature", factor: 1},
+		"degF": {dimension: "temperature", factor: 5.0 / 9.0, offset: -32},
+		"K":    {dimension: "temperature", factor: 1, offset: -273.15},
+		// pressure, base Pa
+		"Pa":   {dimension: "pressure", factor: 1},
+		"hPa":  {dimension: "pressure", factor: 100},
+		"kPa":  {dimension: "pressure", factor: 1000},
+		"mbar": {dimension: "pressure", factor: 100},
+		"bar":  {dimension: "pressure", factor: 100000},
+		// energy, base Wh
+		"Wh":  {dimension: "energy", factor: 1},
+		"kWh": {dimension: "energy", factor: 1000},
+		"MWh": {dimension: "energy", factor: 1000000},
+		"J":   {dimension: "energy", factor: 1.0 / 3600},
+		"kJ":  {dimension: "energy", factor: 1000.0 / 3600},
+		// power, base W
+		"W":  {dimension: "power", factor: 1},
+		"kW": {dimension: "power", factor: 1000},
+		"MW": {dimension: "power", factor: 1000000},
+		// length, base m
+		"mm": {dimension: "length", factor: 0.001},
+		"cm": {dimension: "length", factor: 0.01},
+		"m":  {dimension: "length", factor: 1},
+		"km": {dimension: "length", factor: 1000},
+		// volume, base L
+		"mL": {dimension: "volume", factor: 0.001},
+		"L":  {dimension: "volume", factor: 1},
+		"m3": {dimension: "volume", factor: 1000},
+		// mass, base kg
+		"g":  {dimension: "mass", factor: 0.001},
+		"kg": {dimension: "mass", factor: 1},
+		"t":  {dimension: "mass", factor: 1000},
+		// speed, base m/s
+		"m/s":  {dimension: "speed", factor: 1},
+		"km/h": {dimension: "speed", factor: 1000.0 / 3600},
+		// electric potential, base V
+		"mV": {dimension: "voltage", factor: 0.001},
+		"V":  {dimension: "voltage", factor: 1},
+		// electric current, base A
+		"mA": {dimension: "current", factor: 0.001},
+		"A":  {dimension: "current", factor: 1},
+		// duration, base s
+		"ms":  {dimension: "duration", factor: 0.001},
+		"s":   {dimension: "duration", factor: 1},
+		"min": {dimension: "duration", factor: 60},
+		"h":   {dimension: "duration", factor: 3600},
+		"d":   {dimension: "duration", factor: 86400},
+	}
+)
+
+// RegisterUnit adds unitId to the unit registry or replaces it. A value v in
+// unitId is (v + offset) * factor in the base unit of dimension, so two units
+// convert into each other when they share a dimension and its base unit.
+func RegisterUnit(unitId string, dimension string, factor float64, offset float64) error {
+	if len(unitId) == 0 || len(dimension) == 0 || factor == 0 {
+		return fmt.Errorf("unit '%s' needs a dimension and a non zero factor", unitId)
+	}
+	unitsMutex.Lock()
+	defer unitsMutex.Unlock()
+	units[unitId] = unit{dimension: dimension, factor: factor, offset: offset}
+	return nil
+}
+
+// IsKnownUnit tells whether unitId is in the unit registry.
+func IsKnownUnit(unitId string) bool {
+	unitsMutex.RLock()
+	defer unitsMutex.RUnlock()
+	_, ok := units[unitId]
+	return ok
+}
+
+// ValidateUnitConversion checks that fromUnitId and toUnitId are known units of
+// the same dimension.
+func ValidateUnitConversion(fromUnitId string, toUnitId string) error {
+	_, _, err := conversionUnits(fromUnitId, toUnitId)
+	return err
+}
+
+// ConvertUnit rescales value from the unit fromUnitId to the unit toUnitId.
+func ConvertUnit(value float64, fromUnitId string, toUnitId string) (float64, error) {
+	from, to, err := conversionUnits(fromUnitId, toUnitId)
+	if err != nil {
+		return 0, err
+	}
+	if fromUnitId == toUnitId {
+		return value, nil
+	}
+	return (value+from.offset)*from.factor/to.factor - to.offset, nil
+}
+
+func conversionUnits(fromUnitId string, toUnitId string) (unit, unit, error) {
+	unitsMutex.RLock()
+	from, fromOk := units[fromUnitId]
+	to, toOk := units[toUnitId]
+	unitsMutex.RUnlock()
+	switch {
+	case !fromOk:
+		return from, to, NewMappingError(UNKNOWN_UNIT_ErrorCode, fmt.Sprintf("unknown unit '%s'", fromUnitId))
+	case !toOk:
+		return from, to, NewMappingError(UNKNOWN_UNIT_ErrorCode, fmt.Sprintf("unknown unit '%s'", toUnitId))
+	case from.dimension != to.dimension:
+		return from, to, NewMappingError(INCOMPATIBLE_UNITS_ErrorCode,
+			fmt.Sprintf("cannot convert '%s' (%s) to '%s' (%s)", fromUnitId, from.dimension, toUnitId, to.dimension))
+	}
+	return from, to, nil
+}